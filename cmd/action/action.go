@@ -1,13 +1,36 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/local"
 	"github.com/rajatjindal/krew-release-bot/pkg/source/actions"
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var (
+	provider      string
+	localTag      string
+	localRepo     string
+	localActor    string
+	localWorkdir  string
+	localTemplate string
+)
+
 func init() {
 	rootCmd.AddCommand(actionCmd)
+
+	actionCmd.Flags().StringVar(&provider, "provider", "", fmt.Sprintf("CI/CD provider to use, detected from environment if not set. one of: %s", strings.Join(cicd.Names(), ", ")))
+
+	// flags for local provider
+	actionCmd.Flags().StringVar(&localTag, "tag", "", "tag to release, defaults to the tag on current HEAD. only used with local provider")
+	actionCmd.Flags().StringVar(&localRepo, "repo", "", "plugin repo in format <owner>/<repo>, defaults to parsing 'origin' remote. only used with local provider")
+	actionCmd.Flags().StringVar(&localActor, "actor", "", "actor releasing the plugin, defaults to the repo owner. only used with local provider")
+	actionCmd.Flags().StringVar(&localWorkdir, "workdir", "", "local git checkout of the plugin, defaults to current directory. only used with local provider")
+	actionCmd.Flags().StringVar(&localTemplate, "template-file", "", "template file relative to workdir, defaults to .krew.yaml. only used with local provider")
 }
 
 // actionCmd is the github action command
@@ -15,9 +38,42 @@ var actionCmd = &cobra.Command{
 	Use:   "action",
 	Short: "github action for updating plugin manifests in krew-index repo",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := actions.RunAction()
+		// INPUT_PROVIDER is resolved here as well, so the local
+		// provider gets the flags whether it is selected by flag or env
+		name := provider
+		if name == "" {
+			name = os.Getenv("INPUT_PROVIDER")
+		}
+
+		var err error
+		if name == "local" {
+			err = runLocalAction()
+		} else {
+			err = actions.RunAction(name)
+		}
+
 		if err != nil {
-			logrus.Fatal(err)
+			fatal(err)
 		}
 	},
 }
+
+func runLocalAction() error {
+	p := &local.Provider{
+		Tag:          localTag,
+		Actor:        localActor,
+		WorkDir:      localWorkdir,
+		TemplateFile: localTemplate,
+	}
+
+	if localRepo != "" {
+		s := strings.Split(localRepo, "/")
+		if len(s) != 2 {
+			return fmt.Errorf("flag --repo is incorrect format. expected format <owner>/<repo>, found %q", localRepo)
+		}
+
+		p.Owner, p.Repo = s[0], s[1]
+	}
+
+	return actions.RunActionWithProvider(p)
+}
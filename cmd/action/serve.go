@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/krew"
+	"github.com/rajatjindal/krew-release-bot/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr            string
+	serveTLSCert         string
+	serveTLSKey          string
+	serveGHToken         string
+	serveIndexOwner      string
+	serveIndexRepo       string
+	serveIndexBranch     string
+	serveMirrorDir       string
+	serveGitBackend      string
+	serveShutdownTimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "address to listen on")
+	serveCmd.Flags().StringVar(&serveTLSCert, "tls-cert", "", "TLS certificate file. serves plain http if not set")
+	serveCmd.Flags().StringVar(&serveTLSKey, "tls-key", "", "TLS private key file")
+	serveCmd.Flags().StringVar(&serveGHToken, "gh-token", "", "github token used to open PR's, defaults to GH_TOKEN env")
+	serveCmd.Flags().StringVar(&serveIndexOwner, "index-owner", krew.GetKrewIndexRepoOwner(), "owner of the krew index repo, defaults to UPSTREAM_KREW_INDEX_REPO_OWNER env or kubernetes-sigs")
+	serveCmd.Flags().StringVar(&serveIndexRepo, "index-repo", krew.GetKrewIndexRepoName(), "name of the krew index repo, defaults to UPSTREAM_KREW_INDEX_REPO_NAME env or krew-index")
+	serveCmd.Flags().StringVar(&serveIndexBranch, "index-base-branch", krew.GetKrewIndexBaseBranch(), "branch of the krew index repo to open PR's against, defaults to UPSTREAM_KREW_INDEX_BASE_BRANCH env or the default branch of the repo")
+	serveCmd.Flags().StringVar(&serveMirrorDir, "mirror-dir", getMirrorDir(), "directory for the persistent mirror of krew index repo, defaults to KREW_RELEASE_BOT_MIRROR_DIR env or a directory under the temp dir")
+	serveCmd.Flags().StringVar(&serveGitBackend, "git-backend", releaser.GetGitBackend(), "how the plugin manifest is committed to the fork of krew index, 'clone' or 'api'. defaults to KREW_RELEASE_BOT_GIT_BACKEND env or clone")
+	serveCmd.Flags().DurationVar(&serveShutdownTimeout, "shutdown-timeout", 30*time.Second, "time to wait for in-flight requests and releases to complete when shutting down")
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "serve runs the webhook that opens PR's in krew-index repo, for self hosting the bot",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := serve()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func getMirrorDir() string {
+	if os.Getenv("KREW_RELEASE_BOT_MIRROR_DIR") != "" {
+		return os.Getenv("KREW_RELEASE_BOT_MIRROR_DIR")
+	}
+
+	return filepath.Join(os.TempDir(), "krew-release-bot")
+}
+
+func serve() error {
+	config, err := releaser.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	app, err := releaser.GetAppConfig()
+	if err != nil {
+		return err
+	}
+
+	if app != nil {
+		err = config.ValidateWithApp()
+		if err != nil {
+			return err
+		}
+	}
+
+	// GH_TOKEN is not the default of the flag, so that the token is not printed in usage
+	if serveGHToken == "" {
+		serveGHToken = os.Getenv("GH_TOKEN")
+	}
+
+	if serveGHToken == "" && app == nil {
+		return errors.New("github token not set. use flag --gh-token or GH_TOKEN env, or configure the github app using KREW_RELEASE_BOT_APP_* env")
+	}
+
+	if serveGitBackend != releaser.GitBackendClone && serveGitBackend != releaser.GitBackendAPI {
+		return fmt.Errorf("invalid git backend %q. expected %q or %q", serveGitBackend, releaser.GitBackendClone, releaser.GitBackendAPI)
+	}
+
+	if (serveTLSCert == "") != (serveTLSKey == "") {
+		return errors.New("flags --tls-cert and --tls-key must be set together")
+	}
+
+	r := releaser.New(serveGHToken).WithUpstreamKrewIndex(serveIndexOwner, serveIndexRepo).WithConfig(config)
+	r.BaseBranch = serveIndexBranch
+	r.MirrorDir = serveMirrorDir
+	r.GitBackend = serveGitBackend
+
+	if app != nil {
+		r, err = r.WithApp(*app)
+		if err != nil {
+			return err
+		}
+	}
+
+	r, err = r.WithActionHook()
+	if err != nil {
+		return err
+	}
+
+	ready := &atomic.Bool{}
+	server := &http.Server{
+		Addr:              serveAddr,
+		Handler:           r.Handler(ready.Load),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		logrus.Info("shutting down server")
+		ready.Store(false)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			shutdown <- err
+			return
+		}
+
+		// releases from github release webhook continue after the response is sent
+		shutdown <- r.Wait(shutdownCtx)
+	}()
+
+	logrus.Infof("serving webhook on %s for krew index %s/%s", serveAddr, serveIndexOwner, serveIndexRepo)
+	ready.Store(true)
+
+	if serveTLSCert != "" {
+		err = server.ListenAndServeTLS(serveTLSCert, serveTLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return <-shutdown
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -50,6 +51,21 @@ var templateCmd = &cobra.Command{
 			logrus.Fatal(invalidSpecError.Error())
 		}
 
-		logrus.Fatal(err)
+		fatal(err)
 	},
 }
+
+// fatal logs the error, along with details of the
+// asset that failed to process if available, and exits
+func fatal(err error) {
+	var assetErr *source.AssetError
+	if errors.As(err, &assetErr) {
+		logrus.WithFields(logrus.Fields{
+			"url":        assetErr.URL,
+			"statusCode": assetErr.StatusCode,
+			"attempts":   assetErr.Attempts,
+		}).Fatal(assetErr.Err)
+	}
+
+	logrus.Fatal(err)
+}
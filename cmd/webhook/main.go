@@ -5,11 +5,40 @@ import (
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/rajatjindal/krew-release-bot/pkg/releaser"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
 	ghToken := os.Getenv("GH_TOKEN")
-	releaser := releaser.New(ghToken)
+	config, err := releaser.GetConfig()
+	if err != nil {
+		logrus.Fatal(err)
+	}
 
-	lambda.Start(releaser.HandleActionLambdaWebhook)
+	r := releaser.New(ghToken).WithConfig(config)
+	r.GitBackend = releaser.GetGitBackend()
+
+	app, err := releaser.GetAppConfig()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if app != nil {
+		err = config.ValidateWithApp()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		r, err = r.WithApp(*app)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	r, err = r.WithActionHook()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	lambda.Start(r.HandleActionLambdaWebhook)
 }
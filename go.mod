@@ -16,7 +16,11 @@ require (
 	sigs.k8s.io/krew v0.3.3
 )
 
-require github.com/google/go-github/v66 v66.0.0
+require (
+	github.com/google/go-github/v66 v66.0.0
+	golang.org/x/mod v0.33.0
+	sigs.k8s.io/yaml v1.3.0
+)
 
 require (
 	github.com/Microsoft/go-winio v0.6.0 // indirect
@@ -41,7 +45,6 @@ require (
 	github.com/src-d/gcfg v1.4.0 // indirect
 	github.com/xanzy/ssh-agent v0.3.3 // indirect
 	golang.org/x/crypto v0.48.0 // indirect
-	golang.org/x/mod v0.33.0 // indirect
 	golang.org/x/net v0.50.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
@@ -60,5 +63,4 @@ require (
 	k8s.io/utils v0.0.0-20230313181309-38a27ef9d749 // indirect
 	sigs.k8s.io/json v0.0.0-20221116044647-bc3834ca7abd // indirect
 	sigs.k8s.io/structured-merge-diff/v4 v4.2.3 // indirect
-	sigs.k8s.io/yaml v1.3.0 // indirect
 )
@@ -0,0 +1,93 @@
+package azurepipelines
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+)
+
+// Provider implements provider interface
+type Provider struct{}
+
+// IsPreRelease checks if the github release for the tag is a pre-release
+func (p *Provider) IsPreRelease(owner, repo, tag string) (bool, error) {
+	return github.IsPreReleaseTag(owner, repo, tag)
+}
+
+// GetTag returns tag
+func (p *Provider) GetTag() (string, error) {
+	ref := getInputForAction("krew_plugin_release_tag")
+	if ref != "" {
+		return ref, nil
+	}
+
+	ref = os.Getenv("BUILD_SOURCEBRANCH")
+	if ref == "" {
+		return "", fmt.Errorf("BUILD_SOURCEBRANCH env variable not found")
+	}
+
+	//BUILD_SOURCEBRANCH=refs/tags/v0.0.6
+	if strings.HasPrefix(ref, "refs/tags/") {
+		return strings.TrimPrefix(ref, "refs/tags/"), nil
+	}
+
+	return "", fmt.Errorf("failed to find the tag for the release")
+}
+
+// GetOwnerAndRepo gets the owner and repo from the env
+func (p *Provider) GetOwnerAndRepo() (string, string, error) {
+	repoFromEnv := os.Getenv("BUILD_REPOSITORY_NAME")
+	if repoFromEnv == "" {
+		return "", "", fmt.Errorf("env BUILD_REPOSITORY_NAME not set")
+	}
+
+	s := strings.Split(repoFromEnv, "/")
+	if len(s) != 2 {
+		return "", "", fmt.Errorf("env BUILD_REPOSITORY_NAME is incorrect format. expected format <owner>/<repo>, found %q", repoFromEnv)
+	}
+
+	return s[0], s[1], nil
+}
+
+// GetActor gets the actor from the owner of the repo,
+// as azure pipelines does not expose the github handle of the user
+func (p *Provider) GetActor() (string, error) {
+	owner, _, err := p.GetOwnerAndRepo()
+	if err != nil {
+		return "", err
+	}
+
+	if owner == "" {
+		return "", fmt.Errorf("failed to find actor for the release")
+	}
+
+	return owner, nil
+}
+
+// getInputForAction gets input to action
+func getInputForAction(key string) string {
+	return os.Getenv(fmt.Sprintf("INPUT_%s", strings.ToUpper(key)))
+}
+
+// GetWorkDirectory gets workdir
+func (p *Provider) GetWorkDirectory() string {
+	workdirInput := getInputForAction("workdir")
+	if workdirInput != "" {
+		return workdirInput
+	}
+
+	return os.Getenv("BUILD_SOURCESDIRECTORY")
+}
+
+// GetTemplateFile returns the template file
+func (p *Provider) GetTemplateFile() string {
+	templateFile := getInputForAction("krew_template_file")
+	if templateFile != "" {
+		return filepath.Join(p.GetWorkDirectory(), templateFile)
+	}
+
+	return filepath.Join(p.GetWorkDirectory(), ".krew.yaml")
+}
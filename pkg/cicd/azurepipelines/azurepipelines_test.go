@@ -0,0 +1,200 @@
+package azurepipelines
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOwnerAndRepo(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedOwner string
+		expectedRepo  string
+		expectedError string
+	}{
+		{
+			name: "BUILD_REPOSITORY_NAME is set as expected",
+			setup: func() {
+				os.Setenv("BUILD_REPOSITORY_NAME", "foo-bar/my-awesome-repo")
+			},
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name: "BUILD_REPOSITORY_NAME is set in incorrect format",
+			setup: func() {
+				os.Setenv("BUILD_REPOSITORY_NAME", "my-awesome-repo")
+			},
+			expectedError: `env BUILD_REPOSITORY_NAME is incorrect format. expected format <owner>/<repo>, found "my-awesome-repo"`,
+		},
+		{
+			name:          "BUILD_REPOSITORY_NAME environment is not set",
+			expectedError: `env BUILD_REPOSITORY_NAME not set`,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			owner, repo, err := p.GetOwnerAndRepo()
+
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetActionActor(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedActor string
+		expectedError string
+	}{
+		{
+			name: "env BUILD_REPOSITORY_NAME is set as expected",
+			setup: func() {
+				os.Setenv("BUILD_REPOSITORY_NAME", "foo-bar/my-awesome-plugin")
+			},
+			expectedActor: "foo-bar",
+		},
+		{
+			name:          "env BUILD_REPOSITORY_NAME is not set",
+			expectedError: "env BUILD_REPOSITORY_NAME not set",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			actor, err := p.GetActor()
+			assert.Equal(t, tc.expectedActor, actor)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTag(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedTag   string
+		expectedError string
+	}{
+		{
+			name: "env BUILD_SOURCEBRANCH is setup",
+			setup: func() {
+				os.Setenv("BUILD_SOURCEBRANCH", "refs/tags/v5.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+		{
+			name: "BUILD_SOURCEBRANCH is not a tag",
+			setup: func() {
+				os.Setenv("BUILD_SOURCEBRANCH", "refs/heads/main")
+			},
+			expectedError: `failed to find the tag for the release`,
+		},
+		{
+			name:          "BUILD_SOURCEBRANCH is not set",
+			expectedError: `BUILD_SOURCEBRANCH env variable not found`,
+		},
+		{
+			name: "krew_plugin_release_tag is provided",
+			setup: func() {
+				os.Setenv("INPUT_KREW_PLUGIN_RELEASE_TAG", "v5.0.0")
+				os.Setenv("BUILD_SOURCEBRANCH", "refs/tags/v1.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			tag, err := p.GetTag()
+			assert.Equal(t, tc.expectedTag, tag)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTemplateFile(t *testing.T) {
+	testcases := []struct {
+		name         string
+		setup        func()
+		expectedFile string
+	}{
+		{
+			name: "env BUILD_SOURCESDIRECTORY is setup",
+			setup: func() {
+				os.Setenv("BUILD_SOURCESDIRECTORY", "/src/plugin")
+			},
+			expectedFile: "/src/plugin/.krew.yaml",
+		},
+		{
+			name: "input workdir overrides BUILD_SOURCESDIRECTORY",
+			setup: func() {
+				os.Setenv("BUILD_SOURCESDIRECTORY", "/src/plugin")
+				os.Setenv("INPUT_WORKDIR", "/src/other")
+			},
+			expectedFile: "/src/other/.krew.yaml",
+		},
+		{
+			name: "input krew_template_file is provided",
+			setup: func() {
+				os.Setenv("BUILD_SOURCESDIRECTORY", "/src/plugin")
+				os.Setenv("INPUT_KREW_TEMPLATE_FILE", "templates/plugin.yaml")
+			},
+			expectedFile: "/src/plugin/templates/plugin.yaml",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			assert.Equal(t, tc.expectedFile, p.GetTemplateFile())
+		})
+	}
+}
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
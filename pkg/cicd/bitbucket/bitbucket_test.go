@@ -0,0 +1,193 @@
+package bitbucket
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOwnerAndRepo(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedOwner string
+		expectedRepo  string
+		expectedError string
+	}{
+		{
+			name: "BITBUCKET_REPO_FULL_NAME is set as expected",
+			setup: func() {
+				os.Setenv("BITBUCKET_REPO_FULL_NAME", "foo-bar/my-awesome-repo")
+			},
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name: "BITBUCKET_REPO_FULL_NAME is set in incorrect format",
+			setup: func() {
+				os.Setenv("BITBUCKET_REPO_FULL_NAME", "my-awesome-repo")
+			},
+			expectedError: `env BITBUCKET_REPO_FULL_NAME is incorrect format. expected format <owner>/<repo>, found "my-awesome-repo"`,
+		},
+		{
+			name:          "BITBUCKET_REPO_FULL_NAME environment is not set",
+			expectedError: `env BITBUCKET_REPO_FULL_NAME not set`,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			owner, repo, err := p.GetOwnerAndRepo()
+
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetActionActor(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedActor string
+		expectedError string
+	}{
+		{
+			name: "env BITBUCKET_REPO_FULL_NAME is set as expected",
+			setup: func() {
+				os.Setenv("BITBUCKET_REPO_FULL_NAME", "foo-bar/my-awesome-plugin")
+			},
+			expectedActor: "foo-bar",
+		},
+		{
+			name:          "env BITBUCKET_REPO_FULL_NAME is not set",
+			expectedError: "env BITBUCKET_REPO_FULL_NAME not set",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			actor, err := p.GetActor()
+			assert.Equal(t, tc.expectedActor, actor)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTag(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedTag   string
+		expectedError string
+	}{
+		{
+			name: "env BITBUCKET_TAG is setup",
+			setup: func() {
+				os.Setenv("BITBUCKET_TAG", "v5.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+		{
+			name:          "BITBUCKET_TAG is not set",
+			expectedError: `BITBUCKET_TAG env variable not found`,
+		},
+		{
+			name: "krew_plugin_release_tag is provided",
+			setup: func() {
+				os.Setenv("INPUT_KREW_PLUGIN_RELEASE_TAG", "v5.0.0")
+				os.Setenv("BITBUCKET_TAG", "v1.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			tag, err := p.GetTag()
+			assert.Equal(t, tc.expectedTag, tag)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTemplateFile(t *testing.T) {
+	testcases := []struct {
+		name         string
+		setup        func()
+		expectedFile string
+	}{
+		{
+			name: "env BITBUCKET_CLONE_DIR is setup",
+			setup: func() {
+				os.Setenv("BITBUCKET_CLONE_DIR", "/src/plugin")
+			},
+			expectedFile: "/src/plugin/.krew.yaml",
+		},
+		{
+			name: "input workdir overrides BITBUCKET_CLONE_DIR",
+			setup: func() {
+				os.Setenv("BITBUCKET_CLONE_DIR", "/src/plugin")
+				os.Setenv("INPUT_WORKDIR", "/src/other")
+			},
+			expectedFile: "/src/other/.krew.yaml",
+		},
+		{
+			name: "input krew_template_file is provided",
+			setup: func() {
+				os.Setenv("BITBUCKET_CLONE_DIR", "/src/plugin")
+				os.Setenv("INPUT_KREW_TEMPLATE_FILE", "templates/plugin.yaml")
+			},
+			expectedFile: "/src/plugin/templates/plugin.yaml",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			assert.Equal(t, tc.expectedFile, p.GetTemplateFile())
+		})
+	}
+}
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
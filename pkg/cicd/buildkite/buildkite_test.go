@@ -0,0 +1,186 @@
+package buildkite
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOwnerAndRepo(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedOwner string
+		expectedRepo  string
+		expectedError string
+	}{
+		{
+			name: "BUILDKITE_REPO is set as expected",
+			setup: func() {
+				os.Setenv("BUILDKITE_REPO", "git@github.com:foo-bar/my-awesome-repo.git")
+			},
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name: "BUILDKITE_REPO is set in incorrect format",
+			setup: func() {
+				os.Setenv("BUILDKITE_REPO", "my-awesome-repo")
+			},
+			expectedError: `env BUILDKITE_REPO is incorrect format. expected format <host>/<owner>/<repo>, found "my-awesome-repo"`,
+		},
+		{
+			name:          "BUILDKITE_REPO environment is not set",
+			expectedError: `env BUILDKITE_REPO not set`,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			owner, repo, err := p.GetOwnerAndRepo()
+
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetActionActor(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedActor string
+		expectedError string
+	}{
+		{
+			name: "env BUILDKITE_REPO is set as expected",
+			setup: func() {
+				os.Setenv("BUILDKITE_REPO", "git@github.com:foo-bar/my-awesome-repo.git")
+			},
+			expectedActor: "foo-bar",
+		},
+		{
+			name:          "env BUILDKITE_REPO is not set",
+			expectedError: "env BUILDKITE_REPO not set",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			actor, err := p.GetActor()
+			assert.Equal(t, tc.expectedActor, actor)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTag(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedTag   string
+		expectedError string
+	}{
+		{
+			name: "env BUILDKITE_TAG is setup",
+			setup: func() {
+				os.Setenv("BUILDKITE_TAG", "v5.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+		{
+			name:          "BUILDKITE_TAG is not set",
+			expectedError: `BUILDKITE_TAG env variable not found`,
+		},
+		{
+			name: "krew_plugin_release_tag is provided",
+			setup: func() {
+				os.Setenv("INPUT_KREW_PLUGIN_RELEASE_TAG", "v5.0.0")
+				os.Setenv("BUILDKITE_TAG", "v1.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			tag, err := p.GetTag()
+			assert.Equal(t, tc.expectedTag, tag)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetWorkingDirectory(t *testing.T) {
+	testcases := []struct {
+		name        string
+		setup       func()
+		expectedDir string
+	}{
+		{
+			name: "env BUILDKITE_BUILD_CHECKOUT_PATH is setup",
+			setup: func() {
+				os.Setenv("BUILDKITE_BUILD_CHECKOUT_PATH", "./data")
+			},
+			expectedDir: "./data",
+		},
+		{
+			name: "input workdir is provided",
+			setup: func() {
+				os.Setenv("BUILDKITE_BUILD_CHECKOUT_PATH", "./data")
+				os.Setenv("INPUT_WORKDIR", "./other")
+			},
+			expectedDir: "./other",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			dir := p.GetWorkDirectory()
+			assert.Equal(t, tc.expectedDir, dir)
+		})
+	}
+}
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
@@ -5,14 +5,16 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
 )
 
 // Provider implements provider interface
 type Provider struct{}
 
-// TODO(rajatjindal): implement for circleci
+// IsPreRelease checks if the github release for the tag is a pre-release
 func (p *Provider) IsPreRelease(owner, repo, tag string) (bool, error) {
-	return false, nil
+	return github.IsPreReleaseTag(owner, repo, tag)
 }
 
 // GetTag returns tag
@@ -0,0 +1,96 @@
+package drone
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+)
+
+// Provider implements provider interface for drone and woodpecker.
+// woodpecker is a fork of drone, and uses CI_* env variables
+// instead of DRONE_* ones
+type Provider struct{}
+
+// IsPreRelease checks if the github release for the tag is a pre-release
+func (p *Provider) IsPreRelease(owner, repo, tag string) (bool, error) {
+	return github.IsPreReleaseTag(owner, repo, tag)
+}
+
+// GetTag returns tag
+func (p *Provider) GetTag() (string, error) {
+	ref := getInputForAction("krew_plugin_release_tag")
+	if ref != "" {
+		return ref, nil
+	}
+
+	ref = getEnv("DRONE_TAG", "CI_COMMIT_TAG")
+	if ref == "" {
+		return "", fmt.Errorf("DRONE_TAG or CI_COMMIT_TAG env variable not found")
+	}
+
+	return ref, nil
+}
+
+// GetOwnerAndRepo gets the owner and repo from the env
+func (p *Provider) GetOwnerAndRepo() (string, string, error) {
+	repoURL := getEnv("DRONE_REPO_LINK", "CI_REPO_URL")
+	if repoURL == "" {
+		return "", "", fmt.Errorf("env DRONE_REPO_LINK or CI_REPO_URL not set")
+	}
+
+	owner, repo, err := github.ParseRepoURL(repoURL)
+	if err != nil {
+		return "", "", fmt.Errorf("env DRONE_REPO_LINK or CI_REPO_URL is incorrect format. %v", err)
+	}
+
+	return owner, repo, nil
+}
+
+// GetActor gets the actor from the env
+func (p *Provider) GetActor() (string, error) {
+	actor := getEnv("DRONE_COMMIT_AUTHOR", "CI_COMMIT_AUTHOR")
+	if actor == "" {
+		return "", fmt.Errorf("env DRONE_COMMIT_AUTHOR or CI_COMMIT_AUTHOR not set")
+	}
+
+	return actor, nil
+}
+
+// getInputForAction gets input to action
+func getInputForAction(key string) string {
+	return os.Getenv(fmt.Sprintf("INPUT_%s", strings.ToUpper(key)))
+}
+
+// GetWorkDirectory gets workdir
+func (p *Provider) GetWorkDirectory() string {
+	workdirInput := getInputForAction("workdir")
+	if workdirInput != "" {
+		return workdirInput
+	}
+
+	return getEnv("DRONE_WORKSPACE", "CI_WORKSPACE")
+}
+
+// GetTemplateFile returns the template file
+func (p *Provider) GetTemplateFile() string {
+	templateFile := getInputForAction("krew_template_file")
+	if templateFile != "" {
+		return filepath.Join(p.GetWorkDirectory(), templateFile)
+	}
+
+	return filepath.Join(p.GetWorkDirectory(), ".krew.yaml")
+}
+
+// getEnv returns the value of first env variable that is set
+func getEnv(keys ...string) string {
+	for _, key := range keys {
+		if os.Getenv(key) != "" {
+			return os.Getenv(key)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,208 @@
+package drone
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOwnerAndRepo(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedOwner string
+		expectedRepo  string
+		expectedError string
+	}{
+		{
+			name: "DRONE_REPO_LINK is set as expected",
+			setup: func() {
+				os.Setenv("DRONE_REPO_LINK", "https://github.com/foo-bar/my-awesome-repo")
+			},
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name: "DRONE_REPO_LINK is set in incorrect format",
+			setup: func() {
+				os.Setenv("DRONE_REPO_LINK", "my-awesome-repo")
+			},
+			expectedError: `env DRONE_REPO_LINK or CI_REPO_URL is incorrect format. expected format <host>/<owner>/<repo>, found "my-awesome-repo"`,
+		},
+		{
+			name: "CI_REPO_URL is set for woodpecker",
+			setup: func() {
+				os.Setenv("CI_REPO_URL", "https://github.com/foo-bar/my-awesome-repo")
+			},
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name:          "DRONE_REPO_LINK environment is not set",
+			expectedError: `env DRONE_REPO_LINK or CI_REPO_URL not set`,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			owner, repo, err := p.GetOwnerAndRepo()
+
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetActionActor(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedActor string
+		expectedError string
+	}{
+		{
+			name: "env DRONE_COMMIT_AUTHOR is set as expected",
+			setup: func() {
+				os.Setenv("DRONE_COMMIT_AUTHOR", "foo-bar")
+			},
+			expectedActor: "foo-bar",
+		},
+		{
+			name: "env CI_COMMIT_AUTHOR is set for woodpecker",
+			setup: func() {
+				os.Setenv("CI_COMMIT_AUTHOR", "foo-bar")
+			},
+			expectedActor: "foo-bar",
+		},
+		{
+			name:          "env DRONE_COMMIT_AUTHOR is not set",
+			expectedError: "env DRONE_COMMIT_AUTHOR or CI_COMMIT_AUTHOR not set",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			actor, err := p.GetActor()
+			assert.Equal(t, tc.expectedActor, actor)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTag(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedTag   string
+		expectedError string
+	}{
+		{
+			name: "env DRONE_TAG is setup",
+			setup: func() {
+				os.Setenv("DRONE_TAG", "v5.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+		{
+			name: "env CI_COMMIT_TAG is set for woodpecker",
+			setup: func() {
+				os.Setenv("CI_COMMIT_TAG", "v5.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+		{
+			name:          "DRONE_TAG is not set",
+			expectedError: `DRONE_TAG or CI_COMMIT_TAG env variable not found`,
+		},
+		{
+			name: "krew_plugin_release_tag is provided",
+			setup: func() {
+				os.Setenv("INPUT_KREW_PLUGIN_RELEASE_TAG", "v5.0.0")
+				os.Setenv("DRONE_TAG", "v1.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			tag, err := p.GetTag()
+			assert.Equal(t, tc.expectedTag, tag)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetWorkingDirectory(t *testing.T) {
+	testcases := []struct {
+		name        string
+		setup       func()
+		expectedDir string
+	}{
+		{
+			name: "env DRONE_WORKSPACE is setup",
+			setup: func() {
+				os.Setenv("DRONE_WORKSPACE", "./data")
+			},
+			expectedDir: "./data",
+		},
+		{
+			name: "input workdir is provided",
+			setup: func() {
+				os.Setenv("DRONE_WORKSPACE", "./data")
+				os.Setenv("INPUT_WORKDIR", "./other")
+			},
+			expectedDir: "./other",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			dir := p.GetWorkDirectory()
+			assert.Equal(t, tc.expectedDir, dir)
+		})
+	}
+}
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v66/github"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/mod/semver"
 	"golang.org/x/oauth2"
 )
 
@@ -26,6 +29,111 @@ func (p *Actions) IsPreRelease(owner, repo, tag string) (bool, error) {
 	return releaseInfo.GetPrerelease(), nil
 }
 
+// IsPreReleaseTag checks if the github release for the tag is marked as pre-release.
+// This is useful for CI/CD providers other than github actions, where
+// the release might not be available (yet). In that case it falls back
+// to checking if the tag has a semver pre-release suffix e.g. v2.0.0-rc.1
+func IsPreReleaseTag(owner, repo, tag string) (bool, error) {
+	client := github.NewClient(getHTTPClient())
+	releaseInfo, err := getReleaseForTag(client, owner, repo, tag)
+	if err == nil {
+		return releaseInfo.GetPrerelease(), nil
+	}
+
+	if !isNotFound(err) {
+		return false, err
+	}
+
+	logrus.Infof("no github release found for tag %q, checking semver pre-release suffix", tag)
+	return IsSemverPreRelease(tag), nil
+}
+
+func isNotFound(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return false
+	}
+
+	return errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// IsSemverPreRelease checks if tag has a semver pre-release suffix.
+// tags without the leading 'v' are supported as well.
+func IsSemverPreRelease(tag string) bool {
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+
+	return semver.Prerelease(tag) != ""
+}
+
+// pollInterval is the interval for polling the release
+// when waiting for assets. it is a var so tests can shorten it
+var pollInterval = 10 * time.Second
+
+// WaitForReleaseAssets polls the github release for the tag until all
+// the assets are present and uploaded, or the timeout is reached.
+// on timeout the error lists the assets still missing
+func WaitForReleaseAssets(owner, repo, tag string, assets []string, timeout time.Duration) error {
+	client := github.NewClient(getHTTPClient())
+	deadline := time.Now().Add(timeout)
+	for {
+		missing, err := getMissingAssets(client, owner, repo, tag, assets)
+		if err != nil {
+			return err
+		}
+
+		if len(missing) == 0 {
+			logrus.Infof("all %d release assets are uploaded", len(assets))
+			return nil
+		}
+
+		if time.Now().Add(pollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for release assets: %s", timeout, strings.Join(missing, ", "))
+		}
+
+		logrus.Infof("waiting for release assets: %s", strings.Join(missing, ", "))
+		time.Sleep(pollInterval)
+	}
+}
+
+// getMissingAssets returns the assets that are not yet uploaded
+// to the release, along with the reason
+func getMissingAssets(client *github.Client, owner, repo, tag string, assets []string) ([]string, error) {
+	missing := []string{}
+	releaseInfo, err := getReleaseForTag(client, owner, repo, tag)
+	if err != nil {
+		if !isNotFound(err) {
+			return nil, err
+		}
+
+		for _, name := range assets {
+			missing = append(missing, fmt.Sprintf("%s (release not found)", name))
+		}
+
+		return missing, nil
+	}
+
+	states := map[string]string{}
+	for _, asset := range releaseInfo.Assets {
+		states[asset.GetName()] = asset.GetState()
+	}
+
+	for _, name := range assets {
+		state, ok := states[name]
+		if !ok {
+			missing = append(missing, fmt.Sprintf("%s (not found)", name))
+			continue
+		}
+
+		if state != "uploaded" {
+			missing = append(missing, fmt.Sprintf("%s (state: %s)", name, state))
+		}
+	}
+
+	return missing, nil
+}
+
 func (p *Actions) getTagForCommitSha(commit string) (string, error) {
 	client := github.NewClient(getHTTPClient())
 	owner, repo, err := p.GetOwnerAndRepo()
@@ -95,6 +203,26 @@ func (p *Actions) GetActor() (string, error) {
 	return actor, nil
 }
 
+// ParseRepoURL parses owner and repo from a git repo url
+// e.g. https://github.com/owner/repo.git or git@github.com:owner/repo.git
+func ParseRepoURL(repoURL string) (string, string, error) {
+	path := ""
+	if u, err := url.Parse(repoURL); err == nil && u.Scheme != "" && u.Host != "" {
+		path = u.Path
+	} else if i := strings.Index(repoURL, ":"); i != -1 {
+		// scp like syntax, e.g. git@github.com:owner/repo.git
+		path = repoURL[i+1:]
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	s := strings.Split(path, "/")
+	if len(s) != 2 || s[0] == "" || s[1] == "" {
+		return "", "", fmt.Errorf("expected format <host>/<owner>/<repo>, found %q", repoURL)
+	}
+
+	return s[0], s[1], nil
+}
+
 // getInputForAction gets input to action
 func getInputForAction(key string) string {
 	return os.Getenv(fmt.Sprintf("INPUT_%s", strings.ToUpper(key)))
@@ -1,8 +1,11 @@
 package github
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
@@ -237,6 +240,149 @@ func TestGetTag(t *testing.T) {
 	}
 }
 
+func TestParseRepoURL(t *testing.T) {
+	testcases := []struct {
+		name          string
+		remoteURL     string
+		expectedOwner string
+		expectedRepo  string
+		expectedError string
+	}{
+		{
+			name:          "https url",
+			remoteURL:     "https://github.com/foo-bar/my-awesome-repo.git",
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name:          "https url without .git suffix",
+			remoteURL:     "https://github.com/foo-bar/my-awesome-repo",
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name:          "scp like ssh url",
+			remoteURL:     "git@github.com:foo-bar/my-awesome-repo.git",
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name:          "ssh url",
+			remoteURL:     "ssh://git@github.com/foo-bar/my-awesome-repo.git",
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name:          "url without repo",
+			remoteURL:     "https://github.com/foo-bar",
+			expectedError: `expected format <host>/<owner>/<repo>, found "https://github.com/foo-bar"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			owner, repo, err := ParseRepoURL(tc.remoteURL)
+
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestWaitForReleaseAssets(t *testing.T) {
+	release := func(states ...string) string {
+		assets := []string{}
+		for i, state := range states {
+			assets = append(assets, fmt.Sprintf(`{"id": %d, "name": "asset-%d.tar.gz", "state": %q}`, i, i, state))
+		}
+
+		return fmt.Sprintf(`{"tag_name": "v0.0.2", "assets": [%s]}`, strings.Join(assets, ","))
+	}
+
+	testcases := []struct {
+		name          string
+		timeout       time.Duration
+		setup         func()
+		expectedError string
+	}{
+		{
+			name:    "all assets are uploaded",
+			timeout: time.Second,
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(release("uploaded", "uploaded"))
+			},
+		},
+		{
+			name:    "assets are uploaded while waiting",
+			timeout: time.Second,
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(404).
+					BodyString(`{"message": "Not Found"}`)
+
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(release("uploaded"))
+
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(release("uploaded", "new"))
+
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(release("uploaded", "uploaded"))
+			},
+		},
+		{
+			name:    "timed out waiting for assets",
+			timeout: 25 * time.Millisecond,
+			setup: func() {
+				gock.New("https://api.github.com").
+					Times(3).
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(release("starter"))
+			},
+			expectedError: "timed out after 25ms waiting for release assets: asset-0.tar.gz (state: starter), asset-1.tar.gz (not found)",
+		},
+		{
+			name:    "github api returns error",
+			timeout: time.Second,
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(500).
+					BodyString(`{"message": "Server Error"}`)
+			},
+			expectedError: "GET https://api.github.com/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2: 500 Server Error []",
+		},
+	}
+
+	pollInterval = 10 * time.Millisecond
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			gock.DisableNetworking()
+			defer gock.Off()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			err := WaitForReleaseAssets("foo-bar", "my-awesome-plugin", "v0.0.2", []string{"asset-0.tar.gz", "asset-1.tar.gz"}, tc.timeout)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
 func assertError(t *testing.T, expectedError string, err error) {
 	if expectedError == "" {
 		assert.Nil(t, err)
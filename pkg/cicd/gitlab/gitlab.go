@@ -0,0 +1,174 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultAPIURL = "https://gitlab.com/api/v4"
+
+// Provider implements provider interface
+type Provider struct{}
+
+// release is the subset of gitlab release api response we care about
+type release struct {
+	TagName         string `json:"tag_name"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+}
+
+// IsPreRelease checks if the gitlab release for the tag is an upcoming release. If the
+// pipeline runs for a tag without release, it falls back to checking if the tag has
+// a semver pre-release suffix e.g. v2.0.0-rc.1
+func (p *Provider) IsPreRelease(owner, repo, tag string) (bool, error) {
+	releaseInfo, err := getReleaseForTag(owner, repo, tag)
+	if err != nil {
+		return false, err
+	}
+
+	if releaseInfo == nil {
+		logrus.Infof("no gitlab release found for tag %q, checking semver pre-release suffix", tag)
+		return github.IsSemverPreRelease(tag), nil
+	}
+
+	return releaseInfo.UpcomingRelease, nil
+}
+
+// GetTag returns tag
+func (p *Provider) GetTag() (string, error) {
+	ref := getInputForAction("krew_plugin_release_tag")
+	if ref != "" {
+		return ref, nil
+	}
+
+	ref = os.Getenv("CI_COMMIT_TAG")
+	if ref == "" {
+		return "", fmt.Errorf("CI_COMMIT_TAG env variable not found")
+	}
+
+	return ref, nil
+}
+
+// GetOwnerAndRepo gets the owner and repo from the env
+func (p *Provider) GetOwnerAndRepo() (string, string, error) {
+	owner := os.Getenv("CI_PROJECT_NAMESPACE")
+	if owner == "" {
+		return "", "", fmt.Errorf("env CI_PROJECT_NAMESPACE not set")
+	}
+
+	repo := os.Getenv("CI_PROJECT_NAME")
+	if repo == "" {
+		return "", "", fmt.Errorf("env CI_PROJECT_NAME not set")
+	}
+
+	return owner, repo, nil
+}
+
+// GetActor gets the owner and repo from the env
+func (p *Provider) GetActor() (string, error) {
+	actor := os.Getenv("GITLAB_USER_LOGIN")
+	if actor == "" {
+		return "", fmt.Errorf("env GITLAB_USER_LOGIN not set")
+	}
+
+	return actor, nil
+}
+
+// getInputForAction gets input to action
+func getInputForAction(key string) string {
+	return os.Getenv(fmt.Sprintf("INPUT_%s", strings.ToUpper(key)))
+}
+
+// GetWorkDirectory gets workdir
+func (p *Provider) GetWorkDirectory() string {
+	workdirInput := getInputForAction("workdir")
+	if workdirInput != "" {
+		return workdirInput
+	}
+
+	return os.Getenv("CI_PROJECT_DIR")
+}
+
+// GetTemplateFile returns the template file
+func (p *Provider) GetTemplateFile() string {
+	templateFile := getInputForAction("krew_template_file")
+	if templateFile != "" {
+		return filepath.Join(p.GetWorkDirectory(), templateFile)
+	}
+
+	return filepath.Join(p.GetWorkDirectory(), ".krew.yaml")
+}
+
+func getAPIURL() string {
+	if os.Getenv("CI_API_V4_URL") != "" {
+		return strings.TrimSuffix(os.Getenv("CI_API_V4_URL"), "/")
+	}
+
+	return defaultAPIURL
+}
+
+// getProjectID returns the url encoded project id, as expected by gitlab api
+func getProjectID(owner, repo string) string {
+	if os.Getenv("CI_PROJECT_ID") != "" {
+		return os.Getenv("CI_PROJECT_ID")
+	}
+
+	return url.PathEscape(fmt.Sprintf("%s/%s", owner, repo))
+}
+
+// getReleaseForTag returns the gitlab release for the tag, or nil if there is no release for it
+func getReleaseForTag(owner, repo, tag string) (*release, error) {
+	uri := fmt.Sprintf("%s/projects/%s/releases/%s", getAPIURL(), getProjectID(owner, repo), url.PathEscape(tag))
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// GITLAB_TOKEN takes precedence as CI_JOB_TOKEN is always
+	// available in the pipeline but has limited permissions
+	if os.Getenv("GITLAB_TOKEN") != "" {
+		req.Header.Set("PRIVATE-TOKEN", os.Getenv("GITLAB_TOKEN"))
+	} else if os.Getenv("CI_JOB_TOKEN") != "" {
+		req.Header.Set("JOB-TOKEN", os.Getenv("CI_JOB_TOKEN"))
+	}
+
+	client := http.Client{
+		Timeout: time.Duration(30 * time.Second),
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %d %s", uri, resp.StatusCode, string(body))
+	}
+
+	releaseInfo := &release{}
+	err = json.Unmarshal(body, releaseInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return releaseInfo, nil
+}
@@ -0,0 +1,252 @@
+package gitlab
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetOwnerAndRepo(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedOwner string
+		expectedRepo  string
+		expectedError string
+	}{
+		{
+			name: "CI_PROJECT_NAMESPACE and CI_PROJECT_NAME is set as expected",
+			setup: func() {
+				os.Setenv("CI_PROJECT_NAMESPACE", "foo-bar")
+				os.Setenv("CI_PROJECT_NAME", "my-awesome-repo")
+			},
+			expectedOwner: "foo-bar",
+			expectedRepo:  "my-awesome-repo",
+		},
+		{
+			name: "CI_PROJECT_NAMESPACE is not set",
+			setup: func() {
+				os.Setenv("CI_PROJECT_NAME", "my-awesome-repo")
+			},
+			expectedError: `env CI_PROJECT_NAMESPACE not set`,
+		},
+		{
+			name: "CI_PROJECT_NAME is not set",
+			setup: func() {
+				os.Setenv("CI_PROJECT_NAMESPACE", "foo-bar")
+			},
+			expectedError: `env CI_PROJECT_NAME not set`,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			owner, repo, err := p.GetOwnerAndRepo()
+
+			assert.Equal(t, tc.expectedOwner, owner)
+			assert.Equal(t, tc.expectedRepo, repo)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetActionActor(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedActor string
+		expectedError string
+	}{
+		{
+			name: "env GITLAB_USER_LOGIN is set as expected",
+			setup: func() {
+				os.Setenv("GITLAB_USER_LOGIN", "foo-bar")
+			},
+			expectedActor: "foo-bar",
+		},
+		{
+			name:          "env GITLAB_USER_LOGIN is not set",
+			expectedError: "env GITLAB_USER_LOGIN not set",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			actor, err := p.GetActor()
+			assert.Equal(t, tc.expectedActor, actor)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetTag(t *testing.T) {
+	testcases := []struct {
+		name          string
+		setup         func()
+		expectedTag   string
+		expectedError string
+	}{
+		{
+			name: "env CI_COMMIT_TAG is setup",
+			setup: func() {
+				os.Setenv("CI_COMMIT_TAG", "v5.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+		{
+			name:          "CI_COMMIT_TAG is not set",
+			expectedError: `CI_COMMIT_TAG env variable not found`,
+		},
+		{
+			name: "krew_plugin_release_tag is provided",
+			setup: func() {
+				os.Setenv("INPUT_KREW_PLUGIN_RELEASE_TAG", "v5.0.0")
+				os.Setenv("CI_COMMIT_TAG", "v1.0.0")
+			},
+			expectedTag: "v5.0.0",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			tag, err := p.GetTag()
+			assert.Equal(t, tc.expectedTag, tag)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestIsPreRelease(t *testing.T) {
+	testcases := []struct {
+		name               string
+		tag                string
+		setup              func()
+		expectedPreRelease bool
+		expectedError      string
+	}{
+		{
+			name: "release is not an upcoming release",
+			setup: func() {
+				gock.New("https://gitlab.com").
+					Get("/api/v4/projects/foo-bar/my-awesome-plugin/releases/v0.0.2").
+					Reply(200).
+					BodyString(`{"tag_name": "v0.0.2", "upcoming_release": false}`)
+			},
+			expectedPreRelease: false,
+		},
+		{
+			name: "release is an upcoming release",
+			setup: func() {
+				gock.New("https://gitlab.com").
+					Get("/api/v4/projects/foo-bar/my-awesome-plugin/releases/v0.0.2").
+					Reply(200).
+					BodyString(`{"tag_name": "v0.0.2", "upcoming_release": true}`)
+			},
+			expectedPreRelease: true,
+		},
+		{
+			name: "uses CI_API_V4_URL and CI_PROJECT_ID when set",
+			setup: func() {
+				os.Setenv("CI_API_V4_URL", "https://gitlab.example.com/api/v4")
+				os.Setenv("CI_PROJECT_ID", "42")
+				os.Setenv("CI_JOB_TOKEN", "job-token")
+
+				gock.New("https://gitlab.example.com").
+					Get("/api/v4/projects/42/releases/v0.0.2").
+					MatchHeader("JOB-TOKEN", "job-token").
+					Reply(200).
+					BodyString(`{"tag_name": "v0.0.2", "upcoming_release": true}`)
+			},
+			expectedPreRelease: true,
+		},
+		{
+			name: "release not found, tag is not a semver pre-release",
+			setup: func() {
+				gock.New("https://gitlab.com").
+					Get("/api/v4/projects/foo-bar/my-awesome-plugin/releases/v0.0.2").
+					Reply(404).
+					BodyString(`{"message":"404 Not Found"}`)
+			},
+			expectedPreRelease: false,
+		},
+		{
+			name: "release not found, tag is a semver pre-release",
+			tag:  "v0.0.3-rc.1",
+			setup: func() {
+				gock.New("https://gitlab.com").
+					Get("/api/v4/projects/foo-bar/my-awesome-plugin/releases/v0.0.3-rc.1").
+					Reply(404).
+					BodyString(`{"message":"404 Not Found"}`)
+			},
+			expectedPreRelease: true,
+		},
+		{
+			name: "getting release fails",
+			setup: func() {
+				gock.New("https://gitlab.com").
+					Get("/api/v4/projects/foo-bar/my-awesome-plugin/releases/v0.0.2").
+					Reply(500).
+					BodyString(`{"message":"500 Internal Server Error"}`)
+			},
+			expectedError: `GET https://gitlab.com/api/v4/projects/foo-bar%2Fmy-awesome-plugin/releases/v0.0.2: 500 {"message":"500 Internal Server Error"}`,
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			gock.DisableNetworking()
+			defer gock.Off()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			tag := tc.tag
+			if tag == "" {
+				tag = "v0.0.2"
+			}
+
+			prerelease, err := p.IsPreRelease("foo-bar", "my-awesome-plugin", tag)
+			assert.Equal(t, tc.expectedPreRelease, prerelease)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package jenkins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+)
+
+// Provider implements provider interface
+type Provider struct{}
+
+// IsPreRelease checks if the github release for the tag is a pre-release
+func (p *Provider) IsPreRelease(owner, repo, tag string) (bool, error) {
+	return github.IsPreReleaseTag(owner, repo, tag)
+}
+
+// GetTag returns tag
+func (p *Provider) GetTag() (string, error) {
+	ref := getInputForAction("krew_plugin_release_tag")
+	if ref != "" {
+		return ref, nil
+	}
+
+	ref = os.Getenv("TAG_NAME")
+	if ref == "" {
+		return "", fmt.Errorf("TAG_NAME env variable not found")
+	}
+
+	return ref, nil
+}
+
+// GetOwnerAndRepo gets the owner and repo from the env
+func (p *Provider) GetOwnerAndRepo() (string, string, error) {
+	repoURL := os.Getenv("GIT_URL")
+	if repoURL == "" {
+		return "", "", fmt.Errorf("env GIT_URL not set")
+	}
+
+	owner, repo, err := github.ParseRepoURL(repoURL)
+	if err != nil {
+		return "", "", fmt.Errorf("env GIT_URL is incorrect format. %v", err)
+	}
+
+	return owner, repo, nil
+}
+
+// GetActor gets the actor from the owner of the repo,
+// as jenkins does not expose the github handle of the user
+func (p *Provider) GetActor() (string, error) {
+	owner, _, err := p.GetOwnerAndRepo()
+	if err != nil {
+		return "", err
+	}
+
+	if owner == "" {
+		return "", fmt.Errorf("failed to find actor for the release")
+	}
+
+	return owner, nil
+}
+
+// getInputForAction gets input to action
+func getInputForAction(key string) string {
+	return os.Getenv(fmt.Sprintf("INPUT_%s", strings.ToUpper(key)))
+}
+
+// GetWorkDirectory gets workdir
+func (p *Provider) GetWorkDirectory() string {
+	workdirInput := getInputForAction("workdir")
+	if workdirInput != "" {
+		return workdirInput
+	}
+
+	return os.Getenv("WORKSPACE")
+}
+
+// GetTemplateFile returns the template file
+func (p *Provider) GetTemplateFile() string {
+	templateFile := getInputForAction("krew_template_file")
+	if templateFile != "" {
+		return filepath.Join(p.GetWorkDirectory(), templateFile)
+	}
+
+	return filepath.Join(p.GetWorkDirectory(), ".krew.yaml")
+}
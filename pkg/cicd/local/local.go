@@ -0,0 +1,183 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+	ugit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// Provider implements provider interface for running
+// the release manually, e.g. from a developer laptop.
+//
+// Values not set explicitly are read from the local git checkout
+// in the work directory.
+type Provider struct {
+	Tag          string
+	Owner        string
+	Repo         string
+	Actor        string
+	WorkDir      string
+	TemplateFile string
+}
+
+// IsPreRelease checks if the github release for the tag is a pre-release
+func (p *Provider) IsPreRelease(owner, repo, tag string) (bool, error) {
+	return github.IsPreReleaseTag(owner, repo, tag)
+}
+
+// GetTag returns tag
+func (p *Provider) GetTag() (string, error) {
+	if p.Tag != "" {
+		return p.Tag, nil
+	}
+
+	ref := getInputForAction("krew_plugin_release_tag")
+	if ref != "" {
+		return ref, nil
+	}
+
+	return p.getTagForHead()
+}
+
+// GetOwnerAndRepo gets the owner and repo from the origin remote
+func (p *Provider) GetOwnerAndRepo() (string, string, error) {
+	if p.Owner != "" && p.Repo != "" {
+		return p.Owner, p.Repo, nil
+	}
+
+	repo, err := p.openRepo()
+	if err != nil {
+		return "", "", err
+	}
+
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get remote 'origin'. error: %v", err)
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", "", fmt.Errorf("remote 'origin' has no url")
+	}
+
+	owner, repoName, err := github.ParseRepoURL(urls[0])
+	if err != nil {
+		return "", "", fmt.Errorf("remote url is incorrect format. %v", err)
+	}
+
+	return owner, repoName, nil
+}
+
+// GetActor gets the actor from the owner, if not set explicitly
+func (p *Provider) GetActor() (string, error) {
+	if p.Actor != "" {
+		return p.Actor, nil
+	}
+
+	owner, _, err := p.GetOwnerAndRepo()
+	if err != nil {
+		return "", err
+	}
+
+	if owner == "" {
+		return "", fmt.Errorf("failed to find actor for the release")
+	}
+
+	return owner, nil
+}
+
+// getInputForAction gets input to action
+func getInputForAction(key string) string {
+	return os.Getenv(fmt.Sprintf("INPUT_%s", strings.ToUpper(key)))
+}
+
+// GetWorkDirectory gets workdir
+func (p *Provider) GetWorkDirectory() string {
+	if p.WorkDir != "" {
+		return p.WorkDir
+	}
+
+	workdirInput := getInputForAction("workdir")
+	if workdirInput != "" {
+		return workdirInput
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+
+	return dir
+}
+
+// GetTemplateFile returns the template file
+func (p *Provider) GetTemplateFile() string {
+	if p.TemplateFile != "" {
+		return filepath.Join(p.GetWorkDirectory(), p.TemplateFile)
+	}
+
+	templateFile := getInputForAction("krew_template_file")
+	if templateFile != "" {
+		return filepath.Join(p.GetWorkDirectory(), templateFile)
+	}
+
+	return filepath.Join(p.GetWorkDirectory(), ".krew.yaml")
+}
+
+func (p *Provider) openRepo() (*ugit.Repository, error) {
+	repo, err := ugit.PlainOpenWithOptions(p.GetWorkDirectory(), &ugit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repo at %s. error: %v", p.GetWorkDirectory(), err)
+	}
+
+	return repo, nil
+}
+
+// getTagForHead returns the tag pointing to the current HEAD
+func (p *Provider) getTagForHead() (string, error) {
+	repo, err := p.openRepo()
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return "", err
+	}
+
+	tag := ""
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		hash, err := repo.ResolveRevision(plumbing.Revision(ref.Name().String()))
+		if err != nil {
+			return err
+		}
+
+		if *hash == head.Hash() {
+			tag = ref.Name().Short()
+			return errTagFound
+		}
+
+		return nil
+	})
+	if err != nil && err != errTagFound {
+		return "", err
+	}
+
+	if tag == "" {
+		return "", fmt.Errorf("failed to find a tag on current HEAD %q", head.Hash().String())
+	}
+
+	return tag, nil
+}
+
+var errTagFound = fmt.Errorf("tag found")
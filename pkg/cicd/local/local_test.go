@@ -0,0 +1,143 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ugit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestGetFromGitCheckout(t *testing.T) {
+	dir := t.TempDir()
+	setupGitRepo(t, dir)
+
+	os.Clearenv()
+	p := &Provider{WorkDir: dir}
+
+	tag, err := p.GetTag()
+	assert.Nil(t, err)
+	assert.Equal(t, "v0.0.2", tag)
+
+	owner, repo, err := p.GetOwnerAndRepo()
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-bar", owner)
+	assert.Equal(t, "my-awesome-plugin", repo)
+
+	actor, err := p.GetActor()
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-bar", actor)
+
+	assert.Equal(t, filepath.Join(dir, ".krew.yaml"), p.GetTemplateFile())
+}
+
+func TestGetFromFlags(t *testing.T) {
+	os.Clearenv()
+	p := &Provider{
+		Tag:          "v5.0.0",
+		Owner:        "karthik",
+		Repo:         "aryan",
+		Actor:        "karthik-aryan",
+		WorkDir:      "/tmp/non-existent",
+		TemplateFile: "plugins/aryan.yaml",
+	}
+
+	tag, err := p.GetTag()
+	assert.Nil(t, err)
+	assert.Equal(t, "v5.0.0", tag)
+
+	owner, repo, err := p.GetOwnerAndRepo()
+	assert.Nil(t, err)
+	assert.Equal(t, "karthik", owner)
+	assert.Equal(t, "aryan", repo)
+
+	actor, err := p.GetActor()
+	assert.Nil(t, err)
+	assert.Equal(t, "karthik-aryan", actor)
+
+	assert.Equal(t, "/tmp/non-existent/plugins/aryan.yaml", p.GetTemplateFile())
+}
+
+func TestGetTagNoTagOnHead(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := ugit.PlainInit(dir, false)
+	assert.Nil(t, err)
+	hash := commitFile(t, repo, dir)
+
+	os.Clearenv()
+	p := &Provider{WorkDir: dir}
+
+	tag, err := p.GetTag()
+	assert.Equal(t, "", tag)
+	assertError(t, `failed to find a tag on current HEAD "`+hash+`"`, err)
+}
+
+func setupGitRepo(t *testing.T, dir string) {
+	repo, err := ugit.PlainInit(dir, false)
+	assert.Nil(t, err)
+
+	commitFile(t, repo, dir)
+	head, err := repo.Head()
+	assert.Nil(t, err)
+
+	_, err = repo.CreateTag("v0.0.1", head.Hash(), nil)
+	assert.Nil(t, err)
+
+	commitFile(t, repo, dir)
+	head, err = repo.Head()
+	assert.Nil(t, err)
+
+	// annotated tag
+	_, err = repo.CreateTag("v0.0.2", head.Hash(), &ugit.CreateTagOptions{
+		Tagger:  signature(),
+		Message: "v0.0.2",
+	})
+	assert.Nil(t, err)
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{"git@github.com:foo-bar/my-awesome-plugin.git"},
+	})
+	assert.Nil(t, err)
+}
+
+func commitFile(t *testing.T, repo *ugit.Repository, dir string) string {
+	w, err := repo.Worktree()
+	assert.Nil(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, ".krew.yaml"), []byte(time.Now().String()), 0644)
+	assert.Nil(t, err)
+
+	_, err = w.Add(".krew.yaml")
+	assert.Nil(t, err)
+
+	hash, err := w.Commit("update template", &ugit.CommitOptions{Author: signature()})
+	assert.Nil(t, err)
+
+	return hash.String()
+}
+
+func signature() *object.Signature {
+	return &object.Signature{
+		Name:  "Karthik Aryan",
+		Email: "karthik@example.com",
+		When:  time.Now(),
+	}
+}
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
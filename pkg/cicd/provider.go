@@ -1,10 +1,19 @@
 package cicd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/azurepipelines"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/bitbucket"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/buildkite"
 	"github.com/rajatjindal/krew-release-bot/pkg/cicd/circleci"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/drone"
 	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/gitlab"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/jenkins"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/local"
 	"github.com/rajatjindal/krew-release-bot/pkg/cicd/travisci"
 )
 
@@ -18,20 +27,112 @@ type Provider interface {
 	IsPreRelease(owner, repo, tag string) (bool, error)
 }
 
+// DetectFunc returns true if the build is running on the CI/CD provider
+type DetectFunc func() bool
+
+// registration is a registered CI/CD provider
+type registration struct {
+	name   string
+	probe  string
+	detect DetectFunc
+	new    func() Provider
+}
+
+var registry []registration
+
+func init() {
+	Register("github-actions", "GITHUB_ACTIONS=true", envEquals("GITHUB_ACTIONS", "true"), func() Provider { return &github.Actions{} })
+	Register("circleci", "CIRCLECI=true", envEquals("CIRCLECI", "true"), func() Provider { return &circleci.Provider{} })
+	Register("travis-ci", "TRAVIS=true", envEquals("TRAVIS", "true"), func() Provider { return &travisci.Provider{} })
+	Register("gitlab-ci", "GITLAB_CI=true", envEquals("GITLAB_CI", "true"), func() Provider { return &gitlab.Provider{} })
+	Register("azure-pipelines", "TF_BUILD is set", envSet("TF_BUILD"), func() Provider { return &azurepipelines.Provider{} })
+	Register("bitbucket-pipelines", "BITBUCKET_BUILD_NUMBER is set", envSet("BITBUCKET_BUILD_NUMBER"), func() Provider { return &bitbucket.Provider{} })
+	Register("jenkins", "JENKINS_URL is set", envSet("JENKINS_URL"), func() Provider { return &jenkins.Provider{} })
+	Register("drone", "DRONE=true or CI=woodpecker", anyOf(envEquals("DRONE", "true"), envEquals("CI", "woodpecker")), func() Provider { return &drone.Provider{} })
+	Register("buildkite", "BUILDKITE=true", envEquals("BUILDKITE", "true"), func() Provider { return &buildkite.Provider{} })
+
+	// local is never detected and has to be selected explicitly
+	Register("local", "", nil, func() Provider { return &local.Provider{} })
+}
+
+// Register registers a CI/CD provider with name.
+// probe is a human readable description of what detect checks for.
+// Providers are detected in the order they are registered.
+func Register(name, probe string, detect DetectFunc, new func() Provider) {
+	for i, r := range registry {
+		if r.name == name {
+			registry[i] = registration{name: name, probe: probe, detect: detect, new: new}
+			return
+		}
+	}
+
+	registry = append(registry, registration{name: name, probe: probe, detect: detect, new: new})
+}
+
+// Names returns the names of registered CI/CD providers
+func Names() []string {
+	names := []string{}
+	for _, r := range registry {
+		names = append(names, r.name)
+	}
+
+	return names
+}
+
 // GetProvider returns the CI/CD provider
-// e.g. github-actions or circle-ci
-func GetProvider() Provider {
-	if os.Getenv("GITHUB_ACTIONS") == "true" {
-		return &github.Actions{}
+// e.g. github-actions, circleci or gitlab-ci
+//
+// if name is empty, INPUT_PROVIDER env is used. if that is
+// empty as well, the provider is detected from the environment
+func GetProvider(name string) (Provider, error) {
+	if name == "" {
+		name = os.Getenv("INPUT_PROVIDER")
 	}
 
-	if os.Getenv("CIRCLECI") == "true" {
-		return &circleci.Provider{}
+	if name != "" {
+		for _, r := range registry {
+			if r.name == name {
+				return r.new(), nil
+			}
+		}
+
+		return nil, fmt.Errorf("unknown CI/CD provider %q. supported providers are: %s", name, strings.Join(Names(), ", "))
 	}
 
-	if os.Getenv("TRAVIS") == "true" {
-		return &travisci.Provider{}
+	probed := []string{}
+	for _, r := range registry {
+		if r.detect != nil && r.detect() {
+			return r.new(), nil
+		}
+
+		if r.probe != "" {
+			probed = append(probed, fmt.Sprintf("%s (%s)", r.name, r.probe))
+		}
 	}
 
-	return nil
+	return nil, fmt.Errorf("failed to identify the CI/CD provider. probed: %s. use input 'provider' or flag '--provider' to select one of: %s", strings.Join(probed, ", "), strings.Join(Names(), ", "))
+}
+
+func envEquals(key, value string) DetectFunc {
+	return func() bool {
+		return os.Getenv(key) == value
+	}
+}
+
+func envSet(key string) DetectFunc {
+	return func() bool {
+		return os.Getenv(key) != ""
+	}
+}
+
+func anyOf(detectFuncs ...DetectFunc) DetectFunc {
+	return func() bool {
+		for _, detect := range detectFuncs {
+			if detect() {
+				return true
+			}
+		}
+
+		return false
+	}
 }
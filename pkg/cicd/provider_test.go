@@ -0,0 +1,147 @@
+package cicd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/azurepipelines"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/bitbucket"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/buildkite"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/circleci"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/drone"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/gitlab"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/jenkins"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/local"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/travisci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProvider(t *testing.T) {
+	testcases := []struct {
+		name             string
+		providerName     string
+		setup            func()
+		expectedProvider Provider
+		expectedError    string
+	}{
+		{
+			name: "github actions is detected",
+			setup: func() {
+				os.Setenv("GITHUB_ACTIONS", "true")
+			},
+			expectedProvider: &github.Actions{},
+		},
+		{
+			name: "circleci is detected",
+			setup: func() {
+				os.Setenv("CIRCLECI", "true")
+			},
+			expectedProvider: &circleci.Provider{},
+		},
+		{
+			name: "travis is detected",
+			setup: func() {
+				os.Setenv("TRAVIS", "true")
+			},
+			expectedProvider: &travisci.Provider{},
+		},
+		{
+			name: "gitlab is detected",
+			setup: func() {
+				os.Setenv("GITLAB_CI", "true")
+			},
+			expectedProvider: &gitlab.Provider{},
+		},
+		{
+			name: "azure pipelines is detected",
+			setup: func() {
+				os.Setenv("TF_BUILD", "True")
+			},
+			expectedProvider: &azurepipelines.Provider{},
+		},
+		{
+			name: "bitbucket pipelines is detected",
+			setup: func() {
+				os.Setenv("BITBUCKET_BUILD_NUMBER", "42")
+			},
+			expectedProvider: &bitbucket.Provider{},
+		},
+		{
+			name: "jenkins is detected",
+			setup: func() {
+				os.Setenv("JENKINS_URL", "https://jenkins.example.com/")
+			},
+			expectedProvider: &jenkins.Provider{},
+		},
+		{
+			name: "drone is detected",
+			setup: func() {
+				os.Setenv("DRONE", "true")
+			},
+			expectedProvider: &drone.Provider{},
+		},
+		{
+			name: "woodpecker is detected",
+			setup: func() {
+				os.Setenv("CI", "woodpecker")
+			},
+			expectedProvider: &drone.Provider{},
+		},
+		{
+			name: "buildkite is detected",
+			setup: func() {
+				os.Setenv("BUILDKITE", "true")
+			},
+			expectedProvider: &buildkite.Provider{},
+		},
+		{
+			name: "input provider overrides detection",
+			setup: func() {
+				os.Setenv("GITHUB_ACTIONS", "true")
+				os.Setenv("INPUT_PROVIDER", "circleci")
+			},
+			expectedProvider: &circleci.Provider{},
+		},
+		{
+			name:         "provider name overrides input provider",
+			providerName: "travis-ci",
+			setup: func() {
+				os.Setenv("INPUT_PROVIDER", "circleci")
+			},
+			expectedProvider: &travisci.Provider{},
+		},
+		{
+			name:             "local provider is selected explicitly",
+			providerName:     "local",
+			expectedProvider: &local.Provider{},
+		},
+		{
+			name:          "unknown provider name",
+			providerName:  "foo-ci",
+			expectedError: `unknown CI/CD provider "foo-ci". supported providers are: github-actions, circleci, travis-ci, gitlab-ci, azure-pipelines, bitbucket-pipelines, jenkins, drone, buildkite, local`,
+		},
+		{
+			name:          "no provider detected",
+			expectedError: `failed to identify the CI/CD provider. probed: github-actions (GITHUB_ACTIONS=true), circleci (CIRCLECI=true), travis-ci (TRAVIS=true), gitlab-ci (GITLAB_CI=true), azure-pipelines (TF_BUILD is set), bitbucket-pipelines (BITBUCKET_BUILD_NUMBER is set), jenkins (JENKINS_URL is set), drone (DRONE=true or CI=woodpecker), buildkite (BUILDKITE=true). use input 'provider' or flag '--provider' to select one of: github-actions, circleci, travis-ci, gitlab-ci, azure-pipelines, bitbucket-pipelines, jenkins, drone, buildkite, local`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			provider, err := GetProvider(tc.providerName)
+			assert.Equal(t, tc.expectedProvider, provider)
+			if tc.expectedError == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, tc.expectedError, err.Error())
+			}
+		})
+	}
+}
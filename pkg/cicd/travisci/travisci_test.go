@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
 )
 
 func TestGetOwnerAndRepo(t *testing.T) {
@@ -135,6 +136,100 @@ func TestGetTag(t *testing.T) {
 	}
 }
 
+func TestIsPreRelease(t *testing.T) {
+	testcases := []struct {
+		name               string
+		tag                string
+		setup              func()
+		expectedPreRelease bool
+		expectedError      string
+	}{
+		{
+			name: "github release is a pre-release",
+			tag:  "v0.0.2",
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(`{"tag_name": "v0.0.2", "prerelease": true}`)
+			},
+			expectedPreRelease: true,
+		},
+		{
+			name: "github release is not a pre-release",
+			tag:  "v2.0.0-rc.1",
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v2.0.0-rc.1").
+					Reply(200).
+					BodyString(`{"tag_name": "v2.0.0-rc.1", "prerelease": false}`)
+			},
+			expectedPreRelease: false,
+		},
+		{
+			name: "no github release, tag has pre-release suffix",
+			tag:  "v2.0.0-rc.1",
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v2.0.0-rc.1").
+					Reply(404).
+					BodyString(`{"message": "Not Found"}`)
+			},
+			expectedPreRelease: true,
+		},
+		{
+			name: "no github release, tag without v has pre-release suffix",
+			tag:  "2.0.0-beta",
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/2.0.0-beta").
+					Reply(404).
+					BodyString(`{"message": "Not Found"}`)
+			},
+			expectedPreRelease: true,
+		},
+		{
+			name: "no github release, tag is a stable version",
+			tag:  "v2.0.0",
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v2.0.0").
+					Reply(404).
+					BodyString(`{"message": "Not Found"}`)
+			},
+			expectedPreRelease: false,
+		},
+		{
+			name: "github api returns error",
+			tag:  "v2.0.0",
+			setup: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v2.0.0").
+					Reply(500).
+					BodyString(`{"message": "Server Error"}`)
+			},
+			expectedError: "GET https://api.github.com/repos/foo-bar/my-awesome-plugin/releases/tags/v2.0.0: 500 Server Error []",
+		},
+	}
+
+	p := &Provider{}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			gock.DisableNetworking()
+			defer gock.Off()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			prerelease, err := p.IsPreRelease("foo-bar", "my-awesome-plugin", tc.tag)
+			assert.Equal(t, tc.expectedPreRelease, prerelease)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
 func assertError(t *testing.T, expectedError string, err error) {
 	if expectedError == "" {
 		assert.Nil(t, err)
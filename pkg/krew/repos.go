@@ -26,3 +26,9 @@ func GetKrewIndexRepoOwner() string {
 
 	return krewIndexRepoOwner
 }
+
+// GetKrewIndexBaseBranch returns the branch of krew-index repo against which the
+// PR's are opened. empty if not overridden, in which case the default branch is used
+func GetKrewIndexBaseBranch() string {
+	return os.Getenv("UPSTREAM_KREW_INDEX_BASE_BRANCH")
+}
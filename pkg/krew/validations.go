@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 
+	"sigs.k8s.io/krew/pkg/index"
 	"sigs.k8s.io/krew/pkg/index/indexscanner"
 	"sigs.k8s.io/krew/pkg/index/validation"
 )
@@ -28,6 +29,16 @@ func GetPluginName(spec []byte) (string, error) {
 	return plugin.GetName(), nil
 }
 
+// GetPluginPlatforms gets the platforms from the plugin spec
+func GetPluginPlatforms(spec []byte) ([]index.Platform, error) {
+	plugin, err := indexscanner.DecodePluginFile(bytes.NewReader(spec))
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.Spec.Platforms, nil
+}
+
 //PluginFileName returns the plugin file with extension
 func PluginFileName(name string) string {
 	return fmt.Sprintf("%s%s", name, ".yaml")
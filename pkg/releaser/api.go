@@ -0,0 +1,138 @@
+package releaser
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/krew"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// GitBackendClone clones the krew-index repo and pushes the changes to the fork using git
+	GitBackendClone = "clone"
+
+	// GitBackendAPI commits the changes to the fork using the git data api of github
+	GitBackendAPI = "api"
+)
+
+// commitWithAPI commits the plugin manifest to the branch of release request in the fork of
+// krew-index, using the git data api of github instead of cloning the repo. The base branch
+// of the fork is synced with upstream first. returns false if the manifest in base branch is
+// already up to date with the release
+func (r *Releaser) commitWithAPI(client *github.Client, request *source.ReleaseRequest, baseBranch string) (bool, error) {
+	ctx := context.TODO()
+	owner, repo := r.LocalKrewIndexRepoOwner, r.LocalKrewIndexRepo
+
+	start := time.Now()
+	_, _, err := client.Repositories.MergeUpstream(ctx, owner, repo, &github.RepoMergeUpstreamRequest{
+		Branch: github.String(baseBranch),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	logrus.Infof("synced branch %s of %s/%s with upstream in %s", baseBranch, owner, repo, time.Since(start))
+
+	base, _, err := client.Git.GetRef(ctx, owner, repo, "heads/"+baseBranch)
+	if err != nil {
+		return false, err
+	}
+
+	baseCommit, _, err := client.Git.GetCommit(ctx, owner, repo, base.GetObject().GetSHA())
+	if err != nil {
+		return false, err
+	}
+
+	file := path.Join("plugins", krew.PluginFileName(request.PluginName))
+	existing, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, file, &github.RepositoryContentGetOptions{
+		Ref: baseCommit.GetSHA(),
+	})
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return false, err
+	}
+
+	if existing != nil {
+		content, err := existing.GetContent()
+		if err != nil {
+			return false, err
+		}
+
+		if content == string(request.ProcessedTemplate) {
+			return false, nil
+		}
+	}
+
+	start = time.Now()
+	blob, _, err := client.Git.CreateBlob(ctx, owner, repo, &github.Blob{
+		Content:  github.String(base64.StdEncoding.EncodeToString(request.ProcessedTemplate)),
+		Encoding: github.String("base64"),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	tree, _, err := client.Git.CreateTree(ctx, owner, repo, baseCommit.GetTree().GetSHA(), []*github.TreeEntry{
+		{
+			Path: github.String(file),
+			Mode: github.String("100644"),
+			Type: github.String("blob"),
+			SHA:  blob.SHA,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	commit, _, err := client.Git.CreateCommit(ctx, owner, repo, &github.Commit{
+		Message: github.String(r.getCommitMessage(request)),
+		Tree:    &github.Tree{SHA: tree.SHA},
+		Parents: []*github.Commit{{SHA: baseCommit.SHA}},
+		Author: &github.CommitAuthor{
+			Name:  github.String(r.TokenUsername),
+			Email: github.String(r.TokenEmail),
+			Date:  &github.Timestamp{Time: time.Now()},
+		},
+	}, nil)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.updateBranchRef(client, *r.getBranchName(request), commit.GetSHA())
+	if err != nil {
+		return false, err
+	}
+
+	logrus.Infof("committed %s to branch %s of %s/%s in %s", file, *r.getBranchName(request), owner, repo, time.Since(start))
+	return true, nil
+}
+
+// updateBranchRef points the branch in the fork of krew-index to the commit, creating
+// the branch if it does not exist. an existing branch is force updated, same as pushing
+// with the git backend, so the branch of already open PR is updated with latest changes
+func (r *Releaser) updateBranchRef(client *github.Client, branchName, sha string) error {
+	ctx := context.TODO()
+	owner, repo := r.LocalKrewIndexRepoOwner, r.LocalKrewIndexRepo
+	ref := &github.Reference{
+		Ref:    github.String("refs/heads/" + branchName),
+		Object: &github.GitObject{SHA: github.String(sha)},
+	}
+
+	_, resp, err := client.Git.GetRef(ctx, owner, repo, "heads/"+branchName)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		_, _, err = client.Git.CreateRef(ctx, owner, repo, ref)
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, _, err = client.Git.UpdateRef(ctx, owner, repo, ref, true)
+	return err
+}
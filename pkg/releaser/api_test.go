@@ -0,0 +1,278 @@
+package releaser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGithub is a stand-in for the git data api of github, serving the fork of krew-index
+type fakeGithub struct {
+	mu sync.Mutex
+
+	// mergeUpstreamStatus is the status code for syncing the fork, 200 if not set
+	mergeUpstreamStatus int
+
+	// refs is the map of ref e.g. heads/main to the sha of commit
+	refs map[string]string
+
+	// files is the map of path to content of files in the base commit
+	files map[string]string
+
+	blobs      map[string]string
+	trees      map[string][]map[string]string
+	commits    map[string]map[string]interface{}
+	forcePush  bool
+	requestLog []string
+}
+
+const (
+	fakeBaseCommit = "base-commit"
+	fakeBaseTree   = "base-tree"
+)
+
+func newFakeGithub(t *testing.T, f *fakeGithub) *httptest.Server {
+	f.blobs = map[string]string{}
+	f.trees = map[string][]map[string]string{}
+	f.commits = map[string]map[string]interface{}{}
+	if f.refs == nil {
+		f.refs = map[string]string{}
+	}
+
+	if f.files == nil {
+		f.files = map[string]string{}
+	}
+
+	repo := "/repos/krew-release-bot/krew-index"
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+repo+"/merge-upstream", func(w http.ResponseWriter, r *http.Request) {
+		if f.mergeUpstreamStatus != 0 {
+			writeJSON(w, f.mergeUpstreamStatus, map[string]string{"message": "merge conflict"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{"merge_type": "fast-forward"})
+	})
+
+	mux.HandleFunc("GET "+repo+"/git/ref/{ref...}", func(w http.ResponseWriter, r *http.Request) {
+		sha, ok := f.refs[r.PathValue("ref")]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"ref":    "refs/" + r.PathValue("ref"),
+			"object": map[string]string{"type": "commit", "sha": sha},
+		})
+	})
+
+	mux.HandleFunc("GET "+repo+"/git/commits/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("sha") != fakeBaseCommit {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"sha":  fakeBaseCommit,
+			"tree": map[string]string{"sha": fakeBaseTree},
+		})
+	})
+
+	mux.HandleFunc("GET "+repo+"/contents/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := f.files[r.PathValue("path")]
+		if !ok || r.URL.Query().Get("ref") != fakeBaseCommit {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{
+			"type":     "file",
+			"path":     r.PathValue("path"),
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	})
+
+	mux.HandleFunc("POST "+repo+"/git/blobs", func(w http.ResponseWriter, r *http.Request) {
+		blob := map[string]string{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&blob))
+		content, err := base64.StdEncoding.DecodeString(blob["content"])
+		assert.Nil(t, err)
+
+		sha := fmt.Sprintf("blob-%d", len(f.blobs)+1)
+		f.blobs[sha] = string(content)
+		writeJSON(w, http.StatusCreated, map[string]string{"sha": sha})
+	})
+
+	mux.HandleFunc("POST "+repo+"/git/trees", func(w http.ResponseWriter, r *http.Request) {
+		tree := struct {
+			BaseTree string              `json:"base_tree"`
+			Tree     []map[string]string `json:"tree"`
+		}{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&tree))
+		assert.Equal(t, fakeBaseTree, tree.BaseTree)
+
+		sha := fmt.Sprintf("tree-%d", len(f.trees)+1)
+		f.trees[sha] = tree.Tree
+		writeJSON(w, http.StatusCreated, map[string]string{"sha": sha})
+	})
+
+	mux.HandleFunc("POST "+repo+"/git/commits", func(w http.ResponseWriter, r *http.Request) {
+		commit := map[string]interface{}{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&commit))
+
+		sha := fmt.Sprintf("commit-%d", len(f.commits)+1)
+		f.commits[sha] = commit
+		writeJSON(w, http.StatusCreated, map[string]string{"sha": sha})
+	})
+
+	mux.HandleFunc("POST "+repo+"/git/refs", func(w http.ResponseWriter, r *http.Request) {
+		ref := map[string]string{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&ref))
+
+		f.refs[ref["ref"][len("refs/"):]] = ref["sha"]
+		writeJSON(w, http.StatusCreated, ref)
+	})
+
+	mux.HandleFunc("PATCH "+repo+"/git/refs/{ref...}", func(w http.ResponseWriter, r *http.Request) {
+		ref := struct {
+			SHA   string `json:"sha"`
+			Force bool   `json:"force"`
+		}{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&ref))
+
+		f.refs[r.PathValue("ref")] = ref.SHA
+		f.forcePush = ref.Force
+		writeJSON(w, http.StatusOK, map[string]string{"ref": "refs/" + r.PathValue("ref")})
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.requestLog = append(f.requestLog, r.Method+" "+r.URL.Path)
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestCommitWithAPI(t *testing.T) {
+	request := &source.ReleaseRequest{
+		TagName:           "v0.0.2",
+		PluginName:        "my-awesome-plugin",
+		PluginOwner:       "foo-bar",
+		PluginRepo:        "my-awesome-plugin",
+		ProcessedTemplate: []byte("version: v0.0.2\n"),
+	}
+	branch := "heads/foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.2"
+
+	testcases := []struct {
+		name              string
+		fake              *fakeGithub
+		expectedChanged   bool
+		expectedForcePush bool
+		expectedError     string
+	}{
+		{
+			name: "new branch is created",
+			fake: &fakeGithub{
+				refs:  map[string]string{"heads/main": fakeBaseCommit},
+				files: map[string]string{"plugins/my-awesome-plugin.yaml": "version: v0.0.1\n"},
+			},
+			expectedChanged: true,
+		},
+		{
+			name: "new plugin",
+			fake: &fakeGithub{
+				refs: map[string]string{"heads/main": fakeBaseCommit},
+			},
+			expectedChanged: true,
+		},
+		{
+			name: "existing branch is force updated",
+			fake: &fakeGithub{
+				refs: map[string]string{
+					"heads/main": fakeBaseCommit,
+					branch:       "old-commit",
+				},
+				files: map[string]string{"plugins/my-awesome-plugin.yaml": "version: v0.0.1\n"},
+			},
+			expectedChanged:   true,
+			expectedForcePush: true,
+		},
+		{
+			name: "manifest is already up to date",
+			fake: &fakeGithub{
+				refs:  map[string]string{"heads/main": fakeBaseCommit},
+				files: map[string]string{"plugins/my-awesome-plugin.yaml": "version: v0.0.2\n"},
+			},
+		},
+		{
+			name: "syncing the fork fails",
+			fake: &fakeGithub{
+				mergeUpstreamStatus: http.StatusConflict,
+			},
+			expectedError: "POST %s/repos/krew-release-bot/krew-index/merge-upstream: 409 merge conflict []",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newFakeGithub(t, tc.fake)
+
+			r := newTestReleaser()
+			r.GithubAPIURL = server.URL
+			r.TokenUsername = "Krew Release Bot"
+			r.TokenEmail = "krew-release-bot@example.com"
+
+			expectedError := tc.expectedError
+			if expectedError != "" {
+				expectedError = fmt.Sprintf(expectedError, server.URL)
+			}
+
+			changed, err := r.commitWithAPI(r.getGithubClient(), request, "main")
+			assertError(t, expectedError, err)
+			assert.Equal(t, tc.expectedChanged, changed)
+			assert.Equal(t, tc.expectedForcePush, tc.fake.forcePush)
+			assert.Equal(t, "POST /repos/krew-release-bot/krew-index/merge-upstream", tc.fake.requestLog[0])
+
+			if expectedError != "" {
+				return
+			}
+
+			if !tc.expectedChanged {
+				assert.Empty(t, tc.fake.blobs)
+				assert.Equal(t, map[string]string{"heads/main": fakeBaseCommit}, tc.fake.refs)
+				return
+			}
+
+			assert.Equal(t, "commit-1", tc.fake.refs[branch])
+			assert.Equal(t, map[string]string{"blob-1": "version: v0.0.2\n"}, tc.fake.blobs)
+			assert.Equal(t, []map[string]string{
+				{"path": "plugins/my-awesome-plugin.yaml", "mode": "100644", "type": "blob", "sha": "blob-1"},
+			}, tc.fake.trees["tree-1"])
+
+			commit := tc.fake.commits["commit-1"]
+			assert.Equal(t, "new version v0.0.2 of my-awesome-plugin", commit["message"])
+			assert.Equal(t, "tree-1", commit["tree"])
+			assert.Equal(t, []interface{}{fakeBaseCommit}, commit["parents"])
+			assert.Equal(t, "Krew Release Bot", commit["author"].(map[string]interface{})["name"])
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package releaser
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// appJWTExpiry is the expiry of jwt used to authenticate as the github app, max 10m
+	appJWTExpiry = 9 * time.Minute
+
+	// installationTokenRefreshBefore is how long before its expiry the installation token is refreshed
+	installationTokenRefreshBefore = 5 * time.Minute
+)
+
+// AppConfig is the configuration for authenticating as a github app
+type AppConfig struct {
+	// AppID is the id of the github app
+	AppID int64
+
+	// InstallationID is the id of installation of the app on
+	// the account owning the fork of krew-index repo
+	InstallationID int64
+
+	// PrivateKey is the PEM encoded private key of the app
+	PrivateKey []byte
+}
+
+// GetAppConfig returns the github app configuration from env. returns nil if app id is not set
+func GetAppConfig() (*AppConfig, error) {
+	if os.Getenv("KREW_RELEASE_BOT_APP_ID") == "" {
+		return nil, nil
+	}
+
+	appID, err := strconv.ParseInt(os.Getenv("KREW_RELEASE_BOT_APP_ID"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid env KREW_RELEASE_BOT_APP_ID. error: %v", err)
+	}
+
+	installationID, err := strconv.ParseInt(os.Getenv("KREW_RELEASE_BOT_APP_INSTALLATION_ID"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid env KREW_RELEASE_BOT_APP_INSTALLATION_ID. error: %v", err)
+	}
+
+	privateKey := []byte(os.Getenv("KREW_RELEASE_BOT_APP_PRIVATE_KEY"))
+	if file := os.Getenv("KREW_RELEASE_BOT_APP_PRIVATE_KEY_FILE"); file != "" {
+		privateKey, err = os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &AppConfig{
+		AppID:          appID,
+		InstallationID: installationID,
+		PrivateKey:     privateKey,
+	}, nil
+}
+
+// WithApp authenticates the releaser as the installation of github app, instead of using a
+// personal access token. The changes are committed by the bot user of the app, and pushed to
+// the fork of krew-index repo owned by the account on which the app is installed
+func (releaser *Releaser) WithApp(app AppConfig) (*Releaser, error) {
+	key, err := parseAppPrivateKey(app.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	appClient := releaser.newGithubClient(oauth2.NewClient(context.TODO(), &appJWTSource{appID: app.AppID, key: key}))
+	ghApp, _, err := appClient.Apps.Get(context.TODO(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	installation, _, err := appClient.Apps.GetInstallation(context.TODO(), app.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the installation token can only open PR's on repos the app is installed on, so the
+	// token, if set, is used for the PR's on upstream krew-index repo, e.g. kubernetes-sigs/krew-index
+	releaser.UpstreamToken = releaser.Token
+	releaser.Token = ""
+	releaser.tokenSource = oauth2.ReuseTokenSource(nil, &installationTokenSource{
+		client:         appClient,
+		installationID: app.InstallationID,
+	})
+
+	// commits are attributed to the bot user of the app using its noreply email
+	botLogin := ghApp.GetSlug() + "[bot]"
+	bot, _, err := releaser.getGithubClient().Users.Get(context.TODO(), botLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	releaser.TokenUserHandle = botLogin
+	releaser.TokenUsername = botLogin
+	releaser.TokenEmail = fmt.Sprintf("%d+%s@users.noreply.github.com", bot.GetID(), botLogin)
+	releaser.LocalKrewIndexRepoOwner = installation.GetAccount().GetLogin()
+	releaser.LocalKrewIndexRepoCloneURL = getCloneURL(releaser.LocalKrewIndexRepoOwner, releaser.LocalKrewIndexRepo)
+
+	logrus.Infof("authenticated as github app %s, using fork %s/%s", ghApp.GetSlug(), releaser.LocalKrewIndexRepoOwner, releaser.LocalKrewIndexRepo)
+	return releaser, nil
+}
+
+func parseAppPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid github app private key. expected PEM encoded key")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid github app private key. error: %v", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid github app private key. expected RSA key")
+	}
+
+	return key, nil
+}
+
+// appJWTSource returns the jwt for authenticating as the github app
+type appJWTSource struct {
+	appID int64
+	key   *rsa.PrivateKey
+}
+
+func (s *appJWTSource) Token() (*oauth2.Token, error) {
+	now := time.Now()
+	token, err := signAppJWT(s.appID, s.key, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: token,
+		Expiry:      now.Add(appJWTExpiry - time.Minute),
+	}, nil
+}
+
+// signAppJWT returns the RS256 signed jwt for github app. issued at is set
+// a minute in the past to allow for the clock drift with github
+func signAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTExpiry).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// installationTokenSource returns the installation token of the github app.
+// the expiry of token is set before its actual expiry, so it is refreshed
+// before any in-flight git push or api call can fail
+type installationTokenSource struct {
+	client         *github.Client
+	installationID int64
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	token, _, err := s.client.Apps.CreateInstallationToken(context.TODO(), s.installationID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("created installation token for installation %d, expires at %s", s.installationID, token.GetExpiresAt())
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Add(-installationTokenRefreshBefore),
+	}, nil
+}
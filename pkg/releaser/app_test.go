@@ -0,0 +1,202 @@
+package releaser
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+// newFakeGithubApp is a stand-in for the github api serving the app endpoints. expiresIn
+// is the expiry of installation tokens, and tokens counts the installation tokens created
+func newFakeGithubApp(t *testing.T, key *rsa.PrivateKey, expiresIn time.Duration, tokens *int32) *httptest.Server {
+	verifyJWT := func(r *http.Request) bool {
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			return false
+		}
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		assert.Nil(t, err)
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature) != nil {
+			return false
+		}
+
+		claims := map[string]interface{}{}
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(payload, &claims))
+		return claims["iss"] == "1234" && int64(claims["exp"].(float64)) > time.Now().Unix()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /app", func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": 1234, "slug": "my-release-bot"})
+	})
+
+	mux.HandleFunc("GET /app/installations/42", func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":      42,
+			"account": map[string]interface{}{"login": "my-org"},
+		})
+	})
+
+	mux.HandleFunc("POST /app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if !verifyJWT(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+			return
+		}
+
+		n := atomic.AddInt32(tokens, 1)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"token":      fmt.Sprintf("installation-token-%d", n),
+			"expires_at": time.Now().Add(expiresIn).UTC().Format(time.RFC3339),
+		})
+	})
+
+	mux.HandleFunc("GET /users/{user}", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer installation-token-") || r.PathValue("user") != "my-release-bot[bot]" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": 5678, "login": "my-release-bot[bot]"})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestWithApp(t *testing.T) {
+	key, privateKey := newAppKey(t)
+	_, otherKey := newAppKey(t)
+
+	testcases := []struct {
+		name          string
+		privateKey    []byte
+		expectedError string
+	}{
+		{
+			name:       "authenticated as app",
+			privateKey: privateKey,
+		},
+		{
+			name:          "invalid private key",
+			privateKey:    []byte("not a key"),
+			expectedError: "invalid github app private key. expected PEM encoded key",
+		},
+		{
+			name:          "private key of another app",
+			privateKey:    otherKey,
+			expectedError: "GET %s/app: 401 Bad credentials []",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := int32(0)
+			server := newFakeGithubApp(t, key, time.Hour, &tokens)
+
+			r := newTestReleaser()
+			r.GithubAPIURL = server.URL
+			r, err := r.WithApp(AppConfig{AppID: 1234, InstallationID: 42, PrivateKey: tc.privateKey})
+
+			expectedError := tc.expectedError
+			if strings.Contains(expectedError, "%s") {
+				expectedError = fmt.Sprintf(expectedError, server.URL)
+			}
+
+			assertError(t, expectedError, err)
+			if expectedError != "" {
+				return
+			}
+
+			assert.Equal(t, "", r.Token)
+			assert.Equal(t, "token", r.UpstreamToken)
+			assert.True(t, r.hasCredentials())
+			assert.Equal(t, "my-release-bot[bot]", r.TokenUserHandle)
+			assert.Equal(t, "my-release-bot[bot]", r.TokenUsername)
+			assert.Equal(t, "5678+my-release-bot[bot]@users.noreply.github.com", r.TokenEmail)
+			assert.Equal(t, "my-org", r.LocalKrewIndexRepoOwner)
+			assert.Equal(t, "https://github.com/my-org/krew-index.git", r.LocalKrewIndexRepoCloneURL)
+
+			auth, err := r.getAuth()
+			assert.Nil(t, err)
+			assert.Equal(t, &githttp.BasicAuth{Username: "x-access-token", Password: "installation-token-1"}, auth)
+			assert.Equal(t, int32(1), tokens)
+		})
+	}
+}
+
+func TestInstallationTokenRefresh(t *testing.T) {
+	key, privateKey := newAppKey(t)
+
+	testcases := []struct {
+		name           string
+		expiresIn      time.Duration
+		expectedTokens int32
+	}{
+		{
+			name:           "token is reused until close to its expiry",
+			expiresIn:      time.Hour,
+			expectedTokens: 1,
+		},
+		{
+			name:           "token is refreshed before it expires",
+			expiresIn:      installationTokenRefreshBefore - time.Minute,
+			expectedTokens: 3,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := int32(0)
+			server := newFakeGithubApp(t, key, tc.expiresIn, &tokens)
+
+			r := newTestReleaser()
+			r.GithubAPIURL = server.URL
+			r, err := r.WithApp(AppConfig{AppID: 1234, InstallationID: 42, PrivateKey: privateKey})
+			assert.Nil(t, err)
+
+			for i := 0; i < 2; i++ {
+				_, err := r.getAuth()
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, tc.expectedTokens, tokens)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package releaser
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultPRTitleTemplate = `release new version {{ .TagName }} of {{ .PluginName }}`
+
+const defaultPRBodyTemplate = "hey krew-index team,\n\n" +
+	"I am [krew-release-bot](https://github.com/rajatjindal/krew-release-bot), and I would like to open this PR to publish version `{{ .TagName }}` of `{{ .PluginName }}` on behalf of @{{ .PluginReleaseActor }}.\n\n" +
+	"Thanks,\n" +
+	"@krew-release-bot"
+
+// Config is the identity of the bot, its fork of krew-index and the text of PR's it opens.
+// The PR title and body are go templates executed with the release request
+type Config struct {
+	BotHandle       string `json:"botHandle"`
+	BotName         string `json:"botName"`
+	BotEmail        string `json:"botEmail"`
+	ForkOwner       string `json:"forkOwner"`
+	ForkCloneURL    string `json:"forkCloneURL"`
+	PRTitleTemplate string `json:"prTitleTemplate"`
+	PRBodyTemplate  string `json:"prBodyTemplate"`
+
+	// Indexes are the custom krew indexes to which plugins can be released
+	Indexes []IndexConfig `json:"indexes"`
+}
+
+// DefaultConfig returns the configuration of the public krew-release-bot
+func DefaultConfig() *Config {
+	return &Config{
+		BotHandle:       "krew-release-bot",
+		BotName:         "Krew Release Bot",
+		BotEmail:        "krewpluginreleasebot@gmail.com",
+		PRTitleTemplate: defaultPRTitleTemplate,
+		PRBodyTemplate:  defaultPRBodyTemplate,
+	}
+}
+
+// GetConfig returns the configuration from the file at KREW_RELEASE_BOT_CONFIG_FILE env, overridden
+// by the KREW_RELEASE_BOT_* env variables. The values not set default to those of DefaultConfig
+func GetConfig() (*Config, error) {
+	c := DefaultConfig()
+	if file := os.Getenv("KREW_RELEASE_BOT_CONFIG_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		err = yaml.UnmarshalStrict(data, c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file %s. error: %v", file, err)
+		}
+	}
+
+	for env, value := range map[string]*string{
+		"KREW_RELEASE_BOT_HANDLE":            &c.BotHandle,
+		"KREW_RELEASE_BOT_NAME":              &c.BotName,
+		"KREW_RELEASE_BOT_EMAIL":             &c.BotEmail,
+		"KREW_RELEASE_BOT_FORK_OWNER":        &c.ForkOwner,
+		"KREW_RELEASE_BOT_FORK_CLONE_URL":    &c.ForkCloneURL,
+		"KREW_RELEASE_BOT_PR_TITLE_TEMPLATE": &c.PRTitleTemplate,
+		"KREW_RELEASE_BOT_PR_BODY_TEMPLATE":  &c.PRBodyTemplate,
+	} {
+		if os.Getenv(env) != "" {
+			*value = os.Getenv(env)
+		}
+	}
+
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate validates the configuration. The templates are
+// rendered for a sample release request to catch invalid fields
+func (c *Config) Validate() error {
+	if c.BotHandle == "" {
+		return fmt.Errorf("botHandle is required")
+	}
+
+	if c.BotName == "" {
+		return fmt.Errorf("botName is required")
+	}
+
+	_, err := mail.ParseAddress(c.BotEmail)
+	if err != nil {
+		return fmt.Errorf("invalid botEmail %q. error: %v", c.BotEmail, err)
+	}
+
+	if c.ForkCloneURL != "" {
+		owner, _, err := parseForkCloneURL(c.ForkCloneURL)
+		if err != nil {
+			return err
+		}
+
+		if c.ForkOwner != "" && !strings.EqualFold(c.ForkOwner, owner) {
+			return fmt.Errorf("forkOwner %q does not match the owner %q of forkCloneURL", c.ForkOwner, owner)
+		}
+	}
+
+	repos := map[string]bool{}
+	for _, index := range c.Indexes {
+		err := index.validate()
+		if err != nil {
+			return err
+		}
+
+		if repos[strings.ToLower(index.Repo)] {
+			return fmt.Errorf("index %s is configured more than once", index.Repo)
+		}
+
+		repos[strings.ToLower(index.Repo)] = true
+	}
+
+	sample := &source.ReleaseRequest{
+		TagName:            "v0.0.1",
+		PluginName:         "my-plugin",
+		PluginOwner:        "foo",
+		PluginRepo:         "bar",
+		PluginReleaseActor: "foo",
+	}
+
+	for name, text := range map[string]string{"prTitleTemplate": c.PRTitleTemplate, "prBodyTemplate": c.PRBodyTemplate} {
+		rendered, err := renderPRText(name, text, sample)
+		if err != nil {
+			return fmt.Errorf("invalid %s. error: %v", name, err)
+		}
+
+		if strings.TrimSpace(rendered) == "" {
+			return fmt.Errorf("invalid %s. renders to empty text", name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateWithApp validates that the config does not set the identity of the bot or its fork,
+// when authenticating as github app. These are of the bot user and installation of the app
+func (c *Config) ValidateWithApp() error {
+	d := DefaultConfig()
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"botHandle", c.BotHandle != d.BotHandle},
+		{"botName", c.BotName != d.BotName},
+		{"botEmail", c.BotEmail != d.BotEmail},
+		{"forkOwner", c.ForkOwner != ""},
+		{"forkCloneURL", c.ForkCloneURL != ""},
+	} {
+		if field.set {
+			return fmt.Errorf("%s cannot be set when authenticating as github app. the bot identity and fork are of the app and its installation", field.name)
+		}
+	}
+
+	return nil
+}
+
+// WithConfig sets the identity of the bot, its fork of krew-index and the text of PR's. The
+// fork defaults to the krew-index repo owned by the bot, and its owner and repo are taken
+// from ForkCloneURL if set
+func (releaser *Releaser) WithConfig(c *Config) *Releaser {
+	releaser.TokenUserHandle = c.BotHandle
+	releaser.TokenUsername = c.BotName
+	releaser.TokenEmail = c.BotEmail
+	releaser.PRTitleTemplate = c.PRTitleTemplate
+	releaser.PRBodyTemplate = c.PRBodyTemplate
+	releaser.Indexes = c.Indexes
+
+	releaser.LocalKrewIndexRepoOwner = c.ForkOwner
+	if c.ForkOwner == "" {
+		releaser.LocalKrewIndexRepoOwner = c.BotHandle
+	}
+
+	releaser.LocalKrewIndexRepoCloneURL = getCloneURL(releaser.LocalKrewIndexRepoOwner, releaser.LocalKrewIndexRepo)
+	if owner, repo, err := parseForkCloneURL(c.ForkCloneURL); err == nil {
+		releaser.LocalKrewIndexRepoOwner = owner
+		releaser.LocalKrewIndexRepo = repo
+		releaser.LocalKrewIndexRepoCloneURL = c.ForkCloneURL
+	}
+
+	return releaser
+}
+
+// parseForkCloneURL returns the owner and repo of the fork from its clone url. The fork
+// must be on github, as the branches and PR's are managed using the github api
+func parseForkCloneURL(cloneURL string) (string, string, error) {
+	u, err := url.Parse(cloneURL)
+	if err != nil || u.Scheme != "https" || !strings.EqualFold(u.Host, "github.com") {
+		return "", "", fmt.Errorf("invalid forkCloneURL %q. expected https://github.com/<owner>/<repo>.git", cloneURL)
+	}
+
+	owner, repo, ok := strings.Cut(strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git"), "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("invalid forkCloneURL %q. expected https://github.com/<owner>/<repo>.git", cloneURL)
+	}
+
+	return owner, repo, nil
+}
+
+// renderPRText renders the template of PR title or body with the release request
+func renderPRText(name, text string, request *source.ReleaseRequest) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	err = t.Execute(buf, request)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,238 @@
+package releaser
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetConfig(t *testing.T) {
+	fileConfig := &Config{
+		BotHandle:       "my-release-bot",
+		BotName:         "My Release Bot",
+		BotEmail:        "release-bot@example.com",
+		ForkOwner:       "my-org",
+		ForkCloneURL:    "https://github.com/my-org/my-krew-index.git",
+		PRTitleTemplate: "[{{ .PluginName }}] {{ .TagName }}",
+		PRBodyTemplate:  "Release {{ .TagName }} of {{ .PluginName }} from {{ .PluginOwner }}/{{ .PluginRepo }}, by @{{ .PluginReleaseActor }}.\n",
+		Indexes: []IndexConfig{
+			{Repo: "my-org/krew-index", BaseBranch: "main"},
+		},
+	}
+
+	testcases := []struct {
+		name           string
+		env            map[string]string
+		expectedConfig *Config
+		expectedError  string
+	}{
+		{
+			name:           "defaults",
+			expectedConfig: DefaultConfig(),
+		},
+		{
+			name:           "config file",
+			env:            map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/config.yaml"},
+			expectedConfig: fileConfig,
+		},
+		{
+			name: "env overrides config file",
+			env: map[string]string{
+				"KREW_RELEASE_BOT_CONFIG_FILE":       "data/config.yaml",
+				"KREW_RELEASE_BOT_HANDLE":            "other-bot",
+				"KREW_RELEASE_BOT_PR_TITLE_TEMPLATE": "release {{ .TagName }}",
+			},
+			expectedConfig: func() *Config {
+				c := *fileConfig
+				c.BotHandle = "other-bot"
+				c.PRTitleTemplate = "release {{ .TagName }}"
+				return &c
+			}(),
+		},
+		{
+			name:          "config file not found",
+			env:           map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/not-found.yaml"},
+			expectedError: "open data/not-found.yaml: no such file or directory",
+		},
+		{
+			name:          "unknown field in config file",
+			env:           map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/unknown-field-config.yaml"},
+			expectedError: `invalid config file data/unknown-field-config.yaml. error: error unmarshaling JSON: while decoding JSON: json: unknown field "botUser"`,
+		},
+		{
+			name:          "invalid repo of index",
+			env:           map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/invalid-index-config.yaml"},
+			expectedError: `invalid repo "my-org" of index. expected owner/repo`,
+		},
+		{
+			name:          "invalid allowed plugin of index",
+			env:           map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/invalid-allowed-plugins-config.yaml"},
+			expectedError: `invalid allowed plugin "my-org/" of index my-org/krew-index. expected owner or owner/repo`,
+		},
+		{
+			name:          "index configured more than once",
+			env:           map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/duplicate-index-config.yaml"},
+			expectedError: `index My-Org/krew-index is configured more than once`,
+		},
+		{
+			name:          "token of index not set",
+			env:           map[string]string{"KREW_RELEASE_BOT_CONFIG_FILE": "data/index-token-config.yaml"},
+			expectedError: `env INTERNAL_KREW_INDEX_TOKEN with token for index my-org/internal-krew-index is not set`,
+		},
+		{
+			name:          "invalid email",
+			env:           map[string]string{"KREW_RELEASE_BOT_EMAIL": "release-bot"},
+			expectedError: `invalid botEmail "release-bot". error: mail: missing '@' or angle-addr`,
+		},
+		{
+			name:          "invalid fork clone url",
+			env:           map[string]string{"KREW_RELEASE_BOT_FORK_CLONE_URL": "git@github.com:my-org/krew-index.git"},
+			expectedError: `invalid forkCloneURL "git@github.com:my-org/krew-index.git". expected https://github.com/<owner>/<repo>.git`,
+		},
+		{
+			name:          "fork clone url not on github",
+			env:           map[string]string{"KREW_RELEASE_BOT_FORK_CLONE_URL": "https://git.example.com/my-org/krew-index.git"},
+			expectedError: `invalid forkCloneURL "https://git.example.com/my-org/krew-index.git". expected https://github.com/<owner>/<repo>.git`,
+		},
+		{
+			name:          "fork clone url without repo",
+			env:           map[string]string{"KREW_RELEASE_BOT_FORK_CLONE_URL": "https://github.com/my-org"},
+			expectedError: `invalid forkCloneURL "https://github.com/my-org". expected https://github.com/<owner>/<repo>.git`,
+		},
+		{
+			name: "fork owner does not match fork clone url",
+			env: map[string]string{
+				"KREW_RELEASE_BOT_FORK_OWNER":     "other-org",
+				"KREW_RELEASE_BOT_FORK_CLONE_URL": "https://github.com/my-org/krew-index.git",
+			},
+			expectedError: `forkOwner "other-org" does not match the owner "my-org" of forkCloneURL`,
+		},
+		{
+			name:          "template with unknown field",
+			env:           map[string]string{"KREW_RELEASE_BOT_PR_TITLE_TEMPLATE": "release {{ .Version }}"},
+			expectedError: `invalid prTitleTemplate. error: template: prTitleTemplate:1:11: executing "prTitleTemplate" at <.Version>: can't evaluate field Version in type *source.ReleaseRequest`,
+		},
+		{
+			name:          "invalid template",
+			env:           map[string]string{"KREW_RELEASE_BOT_PR_BODY_TEMPLATE": "release {{ .TagName "},
+			expectedError: `invalid prBodyTemplate. error: template: prBodyTemplate:1: unclosed action`,
+		},
+		{
+			name:          "template renders empty text",
+			env:           map[string]string{"KREW_RELEASE_BOT_PR_TITLE_TEMPLATE": "{{ if false }}release{{ end }}"},
+			expectedError: `invalid prTitleTemplate. renders to empty text`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			config, err := GetConfig()
+			assertError(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedConfig, config)
+		})
+	}
+}
+
+func TestValidateWithApp(t *testing.T) {
+	testcases := []struct {
+		name          string
+		config        func(c *Config)
+		expectedError string
+	}{
+		{
+			name:   "defaults",
+			config: func(c *Config) {},
+		},
+		{
+			name: "pr text and indexes",
+			config: func(c *Config) {
+				c.PRTitleTemplate = "release {{ .TagName }}"
+				c.Indexes = []IndexConfig{{Repo: "my-org/krew-index"}}
+			},
+		},
+		{
+			name:          "bot handle",
+			config:        func(c *Config) { c.BotHandle = "my-release-bot" },
+			expectedError: "botHandle cannot be set when authenticating as github app. the bot identity and fork are of the app and its installation",
+		},
+		{
+			name:          "fork owner",
+			config:        func(c *Config) { c.ForkOwner = "my-org" },
+			expectedError: "forkOwner cannot be set when authenticating as github app. the bot identity and fork are of the app and its installation",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tc.config(c)
+			assertError(t, tc.expectedError, c.ValidateWithApp())
+		})
+	}
+}
+
+func TestWithConfig(t *testing.T) {
+	request := &source.ReleaseRequest{
+		TagName:            "v0.0.3",
+		PluginName:         "my-awesome-plugin",
+		PluginOwner:        "foo-bar",
+		PluginRepo:         "my-awesome-plugin",
+		PluginReleaseActor: "karthik-aryan",
+	}
+
+	t.Run("defaults match the public bot", func(t *testing.T) {
+		r := newTestReleaser().WithConfig(DefaultConfig())
+		assert.Equal(t, "krew-release-bot", r.TokenUserHandle)
+		assert.Equal(t, "Krew Release Bot", r.TokenUsername)
+		assert.Equal(t, "krewpluginreleasebot@gmail.com", r.TokenEmail)
+		assert.Equal(t, "krew-release-bot", r.LocalKrewIndexRepoOwner)
+		assert.Equal(t, "https://github.com/krew-release-bot/krew-index.git", r.LocalKrewIndexRepoCloneURL)
+
+		title, err := r.getTitle(request)
+		assert.Nil(t, err)
+		assert.Equal(t, "release new version v0.0.3 of my-awesome-plugin", *title)
+
+		body, err := r.getPRBody(request)
+		assert.Nil(t, err)
+		assert.Equal(t, "hey krew-index team,\n\nI am [krew-release-bot](https://github.com/rajatjindal/krew-release-bot), and I would like to open this PR to publish version `v0.0.3` of `my-awesome-plugin` on behalf of @karthik-aryan.\n\nThanks,\n@krew-release-bot", *body)
+	})
+
+	t.Run("custom config", func(t *testing.T) {
+		t.Setenv("KREW_RELEASE_BOT_CONFIG_FILE", "data/config.yaml")
+		config, err := GetConfig()
+		assert.Nil(t, err)
+
+		r := newTestReleaser().WithConfig(config)
+		assert.Equal(t, "my-release-bot", r.TokenUserHandle)
+		assert.Equal(t, "My Release Bot", r.TokenUsername)
+		assert.Equal(t, "release-bot@example.com", r.TokenEmail)
+		assert.Equal(t, "my-org", r.LocalKrewIndexRepoOwner)
+		assert.Equal(t, "my-krew-index", r.LocalKrewIndexRepo)
+		assert.Equal(t, "https://github.com/my-org/my-krew-index.git", r.LocalKrewIndexRepoCloneURL)
+		assert.Equal(t, "my-org:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3", *r.getHead(request))
+
+		title, err := r.getTitle(request)
+		assert.Nil(t, err)
+		assert.Equal(t, "[my-awesome-plugin] v0.0.3", *title)
+
+		body, err := r.getPRBody(request)
+		assert.Nil(t, err)
+		assert.Equal(t, "Release v0.0.3 of my-awesome-plugin from foo-bar/my-awesome-plugin, by @karthik-aryan.\n", *body)
+
+		// PR's of older releases are matched using the custom title
+		older := &github.PullRequest{
+			Title: github.String("[my-awesome-plugin] v0.0.2"),
+			Head:  &github.PullRequestBranch{Label: github.String("my-org:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.2")},
+		}
+		assert.True(t, r.isSamePlugin(older, request))
+
+		older.Title = github.String("release new version v0.0.2 of my-awesome-plugin")
+		assert.False(t, r.isSamePlugin(older, request))
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/google/go-github/v66/github"
 	"github.com/rajatjindal/krew-release-bot/pkg/source"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/oauth2"
 	"gopkg.in/src-d/go-git.v4"
 	ugit "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/config"
@@ -27,33 +26,68 @@ const (
 	OriginNameLocal = "local"
 )
 
-// CloneRepos clones the repo
-func (r *Releaser) cloneRepos(dir string, request *source.ReleaseRequest) (*ugit.Repository, error) {
-	logrus.Infof("Cloning %s", r.UpstreamKrewIndexRepoCloneURL)
+// getBaseBranch returns the branch of upstream krew-index repo against which
+// the PR's are opened. defaults to the default branch of the repo
+func (r *Releaser) getBaseBranch(client *github.Client) (string, error) {
+	if r.BaseBranch != "" {
+		return r.BaseBranch, nil
+	}
+
+	repo, _, err := client.Repositories.Get(context.TODO(), r.UpstreamKrewIndexRepoOwner, r.UpstreamKrewIndexRepo)
+	if err != nil {
+		return "", err
+	}
+
+	if repo.GetDefaultBranch() == "" {
+		return "", fmt.Errorf("default branch of %s/%s not found", r.UpstreamKrewIndexRepoOwner, r.UpstreamKrewIndexRepo)
+	}
+
+	return repo.GetDefaultBranch(), nil
+}
+
+// CloneRepos shallow clones the base branch of the repo, and creates the branch for release request
+func (r *Releaser) cloneRepos(dir string, request *source.ReleaseRequest, baseBranch string) (*ugit.Repository, error) {
+	repo, err := r.cloneUpstream(dir, baseBranch, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	branchName := r.getBranchName(request)
+	logrus.Infof("creating branch %s", *branchName)
+	err = r.createBranch(repo, *branchName)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// cloneUpstream clones the base branch of upstream krew-index repo with given depth,
+// 0 being full clone, and adds the fork of krew-index as remote for pushing the changes
+func (r *Releaser) cloneUpstream(dir, baseBranch string, depth int) (*ugit.Repository, error) {
+	auth, err := r.getAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Cloning %s at branch %s", r.UpstreamKrewIndexRepoCloneURL, baseBranch)
+	start := time.Now()
 	repo, err := ugit.PlainClone(dir, false, &ugit.CloneOptions{
 		URL:           r.UpstreamKrewIndexRepoCloneURL,
 		Progress:      os.Stdout,
-		ReferenceName: plumbing.Master,
+		ReferenceName: plumbing.NewBranchReferenceName(baseBranch),
 		SingleBranch:  true,
-		Auth:          r.getAuth(),
+		Depth:         depth,
+		Auth:          auth,
 		RemoteName:    OriginNameUpstream,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	logrus.Infof("Adding remote %s at %s", OriginNameLocal, r.LocalKrewIndexRepoCloneURL)
-	_, err = repo.CreateRemote(&config.RemoteConfig{
-		Name: OriginNameLocal,
-		URLs: []string{r.LocalKrewIndexRepoCloneURL},
-	})
-	if err != nil {
-		return nil, err
-	}
+	logrus.Infof("cloned %s in %s", r.UpstreamKrewIndexRepoCloneURL, time.Since(start))
 
-	branchName := r.getBranchName(request)
-	logrus.Infof("creating branch %s", *branchName)
-	err = r.createBranch(repo, *branchName)
+	err = r.setForkRemote(repo)
 	if err != nil {
 		return nil, err
 	}
@@ -119,35 +153,85 @@ func (r *Releaser) addCommitAndPush(repo *ugit.Repository, commit commitConfig,
 	branchName := r.getBranchName(request)
 	pushRef := getPushRefSpec(*branchName)
 
-	return repo.Push(&ugit.PushOptions{
+	auth, err := r.getAuth()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = repo.Push(&ugit.PushOptions{
 		RemoteName: commit.RemoteName,
 		RefSpecs:   []config.RefSpec{config.RefSpec(pushRef)},
-		Auth:       r.getAuth(),
+		Auth:       auth,
 	})
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("pushed branch %s in %s", *branchName, time.Since(start))
+	return nil
 }
 
+// getPushRefSpec returns the refspec to force push the branch, so
+// the branch of already open PR is updated with latest changes
 func getPushRefSpec(branchName string) string {
-	return fmt.Sprintf("refs/heads/%s:refs/heads/%s", branchName, branchName)
+	return fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branchName, branchName)
 }
 
-// SubmitPR submits the PR
-func (r *Releaser) submitPR(request *source.ReleaseRequest) (string, error) {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: r.Token})
-	tc := oauth2.NewClient(context.TODO(), ts)
-	client := github.NewClient(tc)
+// SubmitPR submits the PR. If a PR is already open for the branch, it is updated by
+// the push and returned as is. Open PR's for older releases of the plugin are closed
+func (r *Releaser) submitPR(client *github.Client, request *source.ReleaseRequest, baseBranch string) (*Result, error) {
+	status := StatusUpdated
+	pr, err := r.findOpenPR(client, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if pr != nil {
+		logrus.Infof("pr %q already open for branch %s, updated with latest changes", pr.GetHTMLURL(), *r.getBranchName(request))
+	} else {
+		pr, err = r.createPR(client, request, baseBranch)
+		if err != nil {
+			return nil, err
+		}
+
+		status = StatusCreated
+		logrus.Infof("pr %q opened for releasing new version", pr.GetHTMLURL())
+	}
+
+	// the pr for the release exists at this point, so failing
+	// to close the older ones does not fail the release
+	err = r.supersedePRs(client, request, pr)
+	if err != nil {
+		logrus.Warnf("superseding older prs of %s by %q failed. error: %v", request.PluginName, pr.GetHTMLURL(), err)
+	}
+
+	return newResult(status, pr.GetHTMLURL()), nil
+}
+
+func (r *Releaser) createPR(client *github.Client, request *source.ReleaseRequest, baseBranch string) (*github.PullRequest, error) {
+	title, err := r.getTitle(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := r.getPRBody(request)
+	if err != nil {
+		return nil, err
+	}
 
 	prr := &github.NewPullRequest{
-		Title: r.getTitle(request),
+		Title: title,
 		Head:  r.getHead(request),
-		Base:  github.String("master"),
-		Body:  r.getPRBody(request),
+		Base:  github.String(baseBranch),
+		Body:  body,
 	}
 
 	logrus.Infof("creating pr with title %q, \nhead %q, \nbase %q, \nbody %q",
-		github.Stringify(r.getTitle(request)),
+		github.Stringify(title),
 		github.Stringify(r.getHead(request)),
-		"master",
-		github.Stringify(r.getPRBody(request)),
+		baseBranch,
+		github.Stringify(body),
 	)
 
 	pr, _, err := client.PullRequests.Create(
@@ -156,22 +240,25 @@ func (r *Releaser) submitPR(request *source.ReleaseRequest) (string, error) {
 		r.UpstreamKrewIndexRepo,
 		prr,
 	)
+	return pr, err
+}
+
+func (r *Releaser) getTitle(request *source.ReleaseRequest) (*string, error) {
+	text := r.PRTitleTemplate
+	if text == "" {
+		text = defaultPRTitleTemplate
+	}
+
+	s, err := renderPRText("prTitleTemplate", text, request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	logrus.Infof("pr %q opened for releasing new version", pr.GetHTMLURL())
-	return pr.GetHTMLURL(), nil
+	return github.String(s), nil
 }
 
-func (r *Releaser) getTitle(request *source.ReleaseRequest) *string {
-	s := fmt.Sprintf(
-		"release new version %s of %s",
-		request.TagName,
-		request.PluginName,
-	)
-
-	return github.String(s)
+func (r *Releaser) getCommitMessage(request *source.ReleaseRequest) string {
+	return fmt.Sprintf("new version %s of %s", request.TagName, request.PluginName)
 }
 
 func (r *Releaser) getBranchName(request *source.ReleaseRequest) *string {
@@ -182,30 +269,38 @@ func (r *Releaser) getBranchName(request *source.ReleaseRequest) *string {
 
 func (r *Releaser) getHead(request *source.ReleaseRequest) *string {
 	branchName := r.getBranchName(request)
-	s := fmt.Sprintf("%s:%s", r.TokenUserHandle, *branchName)
+	s := fmt.Sprintf("%s:%s", r.LocalKrewIndexRepoOwner, *branchName)
 	return github.String(s)
 }
 
-func (r *Releaser) getPRBody(request *source.ReleaseRequest) *string {
-	prBody := `hey krew-index team,
+func (r *Releaser) getPRBody(request *source.ReleaseRequest) (*string, error) {
+	text := r.PRBodyTemplate
+	if text == "" {
+		text = defaultPRBodyTemplate
+	}
 
-I am [krew-release-bot](https://github.com/rajatjindal/krew-release-bot), and I would like to open this PR to publish version %s of %s on behalf of @%s.
+	s, err := renderPRText("prBodyTemplate", text, request)
+	if err != nil {
+		return nil, err
+	}
 
-Thanks,
-@krew-release-bot`
+	return github.String(s), nil
+}
 
-	s := fmt.Sprintf(prBody,
-		fmt.Sprintf("`%s`", request.TagName),
-		fmt.Sprintf("`%s`", request.PluginName),
-		request.PluginReleaseActor,
-	)
+func (r *Releaser) getAuth() (transport.AuthMethod, error) {
+	token, err := r.getTokenSource().Token()
+	if err != nil {
+		return nil, err
+	}
 
-	return github.String(s)
-}
+	// installation tokens of github app are used with a fixed username
+	username := r.TokenUserHandle
+	if r.tokenSource != nil {
+		username = "x-access-token"
+	}
 
-func (r *Releaser) getAuth() transport.AuthMethod {
 	return &githttp.BasicAuth{
-		Username: r.TokenUserHandle,
-		Password: r.Token,
-	}
+		Username: username,
+		Password: token.AccessToken,
+	}, nil
 }
@@ -0,0 +1,227 @@
+package releaser
+
+import (
+	"fmt"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestGetBaseBranch(t *testing.T) {
+	testcases := []struct {
+		name           string
+		baseBranch     string
+		setupMocks     func()
+		expectedBranch string
+		expectedError  string
+	}{
+		{
+			name:           "base branch overridden",
+			baseBranch:     "release",
+			expectedBranch: "release",
+		},
+		{
+			name: "default branch of the repo",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index").
+					Reply(200).
+					JSON(map[string]string{"default_branch": "main"})
+			},
+			expectedBranch: "main",
+		},
+		{
+			name: "repo not found",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index").
+					Reply(404).
+					JSON(map[string]string{"message": "Not Found"})
+			},
+			expectedError: "GET https://api.github.com/repos/kubernetes-sigs/krew-index: 404 Not Found []",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			if tc.setupMocks != nil {
+				tc.setupMocks()
+			}
+
+			r := newTestReleaser()
+			r.BaseBranch = tc.baseBranch
+			branch, err := r.getBaseBranch(r.getGithubClient())
+			assertError(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedBranch, branch)
+			assert.True(t, gock.IsDone())
+		})
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	assert.Nil(t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// newIndexRepos creates a bare krew-index repo with only the given branch, and its fork
+// sharing the objects with it, as is the case for forks on github. the repos are served
+// over http as shallow clones can not be pushed to local paths
+func newIndexRepos(t *testing.T, branch string) (upstream, fork, upstreamURL, forkURL string) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not found")
+	}
+
+	work := t.TempDir()
+	runGit(t, work, "init", "-q", "-b", branch)
+	assert.Nil(t, os.MkdirAll(filepath.Join(work, "plugins"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(work, "plugins", "my-awesome-plugin.yaml"), []byte("version: v0.0.1\n"), 0644))
+	runGit(t, work, "add", ".")
+	runGit(t, work, "commit", "-q", "-m", "initial commit")
+
+	root := t.TempDir()
+	upstream = filepath.Join(root, "upstream.git")
+	fork = filepath.Join(root, "fork.git")
+	runGit(t, work, "clone", "-q", "--bare", work, upstream)
+	runGit(t, root, "clone", "-q", "--bare", "--shared", upstream, fork)
+	runGit(t, fork, "config", "http.receivepack", "true")
+
+	server := httptest.NewServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	t.Cleanup(server.Close)
+
+	return upstream, fork, server.URL + "/upstream.git", server.URL + "/fork.git"
+}
+
+// advanceUpstream commits a new plugin to the branch of upstream repo
+func advanceUpstream(t *testing.T, upstream, branch, plugin string) string {
+	work := t.TempDir()
+	runGit(t, work, "clone", "-q", "-b", branch, upstream, ".")
+	assert.Nil(t, os.WriteFile(filepath.Join(work, "plugins", plugin+".yaml"), []byte("version: v1.0.0\n"), 0644))
+	runGit(t, work, "add", ".")
+	runGit(t, work, "commit", "-q", "-m", "new plugin")
+	runGit(t, work, "push", "-q", "origin", branch)
+	return runGit(t, upstream, "rev-parse", branch)
+}
+
+func newGitTestReleaser(upstreamURL, forkURL string) *Releaser {
+	r := newTestReleaser()
+	r.Token = ""
+	r.UpstreamKrewIndexRepoCloneURL = upstreamURL
+	r.LocalKrewIndexRepoCloneURL = forkURL
+	r.TokenUsername = "Krew Release Bot"
+	r.TokenEmail = "krew-release-bot@example.com"
+	return r
+}
+
+var gitTestRequest = &source.ReleaseRequest{
+	TagName:     "v0.0.2",
+	PluginName:  "my-awesome-plugin",
+	PluginOwner: "foo-bar",
+	PluginRepo:  "my-awesome-plugin",
+}
+
+func TestCloneAndPushWithBaseBranch(t *testing.T) {
+	upstream, fork, upstreamURL, forkURL := newIndexRepos(t, "main")
+	r := newGitTestReleaser(upstreamURL, forkURL)
+
+	request := gitTestRequest
+	branch := *r.getBranchName(request)
+	upstreamHead := runGit(t, upstream, "rev-parse", "main")
+
+	// pushing twice from fresh clones force updates the branch
+	for _, version := range []string{"v0.0.2\n", "v0.0.2 \n"} {
+		dir := t.TempDir()
+		repo, err := r.cloneRepos(dir, request, "main")
+		assert.Nil(t, err)
+
+		head, err := repo.Head()
+		assert.Nil(t, err)
+		assert.Equal(t, "refs/heads/"+branch, head.Name().String())
+		assert.Equal(t, upstreamHead, head.Hash().String())
+		assert.FileExists(t, filepath.Join(dir, ".git", "shallow"))
+
+		err = os.WriteFile(filepath.Join(dir, "plugins", "my-awesome-plugin.yaml"), []byte("version: "+version), 0644)
+		assert.Nil(t, err)
+
+		err = r.addCommitAndPush(repo, commitConfig{Msg: "new version v0.0.2 of my-awesome-plugin", RemoteName: OriginNameLocal}, request)
+		assert.Nil(t, err)
+
+		assert.Equal(t, upstreamHead, runGit(t, fork, "rev-parse", branch+"~1"))
+		assert.Equal(t, "version: "+strings.TrimSpace(version), runGit(t, fork, "show", branch+":plugins/my-awesome-plugin.yaml"))
+	}
+
+	_, err := r.cloneRepos(t.TempDir(), request, "master")
+	assert.NotNil(t, err)
+}
+
+func TestCheckoutIndexWithMirror(t *testing.T) {
+	upstream, fork, upstreamURL, forkURL := newIndexRepos(t, "main")
+	r := newGitTestReleaser(upstreamURL, forkURL)
+	r.MirrorDir = t.TempDir()
+
+	request := gitTestRequest
+	branch := *r.getBranchName(request)
+	upstreamHead := runGit(t, upstream, "rev-parse", "main")
+	mirror := filepath.Join(r.MirrorDir, "kubernetes-sigs", "krew-index")
+
+	for i := 0; i < 2; i++ {
+		dir, repo, done, err := r.checkoutIndex(request, "main")
+		assert.Nil(t, err)
+		assert.Equal(t, mirror, dir)
+
+		head, err := repo.Head()
+		assert.Nil(t, err)
+		assert.Equal(t, "refs/heads/"+branch, head.Name().String())
+		assert.Equal(t, upstreamHead, head.Hash().String())
+		assert.NoFileExists(t, filepath.Join(dir, "plugins", "untracked.yaml"))
+		assert.Equal(t, "version: v0.0.1\n", readFile(t, filepath.Join(dir, "plugins", "my-awesome-plugin.yaml")))
+
+		// leftovers of the previous request are reset
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, "plugins", "untracked.yaml"), []byte("version: v0.0.1\n"), 0644))
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, "plugins", "my-awesome-plugin.yaml"), []byte("version: v0.0.2\n"), 0644))
+		err = r.addCommitAndPush(repo, commitConfig{Msg: "new version v0.0.2 of my-awesome-plugin", RemoteName: OriginNameLocal}, request)
+		assert.Nil(t, err)
+		assert.Equal(t, upstreamHead, runGit(t, fork, "rev-parse", branch+"~1"))
+		done()
+
+		upstreamHead = advanceUpstream(t, upstream, "main", fmt.Sprintf("other-plugin-%d", i))
+	}
+
+	// the existing mirror pushes to the new fork, when the fork changes
+	otherFork := filepath.Join(filepath.Dir(fork), "other-fork.git")
+	runGit(t, filepath.Dir(fork), "clone", "-q", "--bare", "--shared", upstream, otherFork)
+	runGit(t, otherFork, "config", "http.receivepack", "true")
+	r.LocalKrewIndexRepoCloneURL = strings.TrimSuffix(forkURL, "fork.git") + "other-fork.git"
+
+	dir, repo, done, err := r.checkoutIndex(request, "main")
+	assert.Nil(t, err)
+	defer done()
+
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "plugins", "my-awesome-plugin.yaml"), []byte("version: v0.0.2\n"), 0644))
+	err = r.addCommitAndPush(repo, commitConfig{Msg: "new version v0.0.2 of my-awesome-plugin", RemoteName: OriginNameLocal}, request)
+	assert.Nil(t, err)
+	assert.Equal(t, upstreamHead, runGit(t, otherFork, "rev-parse", branch+"~1"))
+}
+
+func readFile(t *testing.T, file string) string {
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	return string(data)
+}
@@ -0,0 +1,165 @@
+package releaser
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+)
+
+// IndexConfig is a custom krew index to which the plugins can be released, in addition
+// to the default index. The release request selects the index using its Index field
+type IndexConfig struct {
+	// Repo is the owner/repo of the index
+	Repo string `json:"repo"`
+
+	// BaseBranch is the branch against which the PR's are opened.
+	// defaults to the default branch of the repo
+	BaseBranch string `json:"baseBranch"`
+
+	// TokenEnv is the env variable with github token for the index.
+	// the credentials of the bot are used if not set
+	TokenEnv string `json:"tokenEnv"`
+
+	// ForkOwner and ForkRepo are the fork of the index to which the changes are pushed.
+	// default to the fork owner of the bot, and the name of index repo
+	ForkOwner string `json:"forkOwner"`
+	ForkRepo  string `json:"forkRepo"`
+
+	// AllowedPlugins are the owners, or owner/repo, of plugin repos which can release
+	// to the index, e.g. 'my-org' or 'my-org/kubectl-foo'. all plugins are allowed if empty
+	AllowedPlugins []string `json:"allowedPlugins"`
+}
+
+func (c IndexConfig) ownerAndRepo() (string, string) {
+	owner, repo, _ := strings.Cut(c.Repo, "/")
+	return owner, repo
+}
+
+func (c IndexConfig) validate() error {
+	owner, repo := c.ownerAndRepo()
+	if owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return fmt.Errorf("invalid repo %q of index. expected owner/repo", c.Repo)
+	}
+
+	if c.TokenEnv != "" && os.Getenv(c.TokenEnv) == "" {
+		return fmt.Errorf("env %s with token for index %s is not set", c.TokenEnv, c.Repo)
+	}
+
+	for _, allowed := range c.AllowedPlugins {
+		owner, repo, found := strings.Cut(allowed, "/")
+		if owner == "" || (found && (repo == "" || strings.Contains(repo, "/"))) {
+			return fmt.Errorf("invalid allowed plugin %q of index %s. expected owner or owner/repo", allowed, c.Repo)
+		}
+	}
+
+	return nil
+}
+
+// allowsPlugin returns true if the plugin repo owner/repo can release to the index
+func (c IndexConfig) allowsPlugin(owner, repo string) bool {
+	if len(c.AllowedPlugins) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.AllowedPlugins {
+		if strings.EqualFold(allowed, owner) || strings.EqualFold(allowed, owner+"/"+repo) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forIndex returns the releaser for the krew index of release request. The index must
+// either be the default index, or be allowed in the config along with the plugin repo
+func (releaser *Releaser) forIndex(request *source.ReleaseRequest) (*Releaser, error) {
+	index := request.Index
+	if index == "" || strings.EqualFold(index, releaser.UpstreamKrewIndexRepoOwner+"/"+releaser.UpstreamKrewIndexRepo) {
+		return releaser, nil
+	}
+
+	for _, c := range releaser.Indexes {
+		if !strings.EqualFold(c.Repo, index) {
+			continue
+		}
+
+		if !c.allowsPlugin(request.PluginOwner, request.PluginRepo) {
+			return nil, &source.AuthError{
+				StatusCode: http.StatusForbidden,
+				Err:        fmt.Errorf("plugin repo %s/%s is not allowed in krew index %q", request.PluginOwner, request.PluginRepo, index),
+			}
+		}
+
+		return releaser.indexReleaser(c), nil
+	}
+
+	return nil, &source.AuthError{
+		StatusCode: http.StatusForbidden,
+		Err:        fmt.Errorf("krew index %q is not allowed", index),
+	}
+}
+
+// indexReleaser returns the cached releaser for the custom krew index, creating it if needed
+func (releaser *Releaser) indexReleaser(c IndexConfig) *Releaser {
+	releaser.indexLock.Lock()
+	defer releaser.indexLock.Unlock()
+
+	key := strings.ToLower(c.Repo)
+	if r, ok := releaser.indexReleasers[key]; ok {
+		return r
+	}
+
+	if releaser.indexReleasers == nil {
+		releaser.indexReleasers = map[string]*Releaser{}
+	}
+
+	// the releaser is reused, so requests for the index share the lock of its mirror
+	releaser.indexReleasers[key] = releaser.newIndexReleaser(c)
+	return releaser.indexReleasers[key]
+}
+
+// newIndexReleaser returns the releaser for the custom krew index, with
+// the identity and settings of the bot, and credentials of the index
+func (releaser *Releaser) newIndexReleaser(c IndexConfig) *Releaser {
+	owner, repo := c.ownerAndRepo()
+	forkOwner := c.ForkOwner
+	if forkOwner == "" {
+		forkOwner = releaser.LocalKrewIndexRepoOwner
+	}
+
+	forkRepo := c.ForkRepo
+	if forkRepo == "" {
+		forkRepo = repo
+	}
+
+	r := &Releaser{
+		Token:                         releaser.Token,
+		TokenEmail:                    releaser.TokenEmail,
+		TokenUserHandle:               releaser.TokenUserHandle,
+		TokenUsername:                 releaser.TokenUsername,
+		UpstreamKrewIndexRepo:         repo,
+		UpstreamKrewIndexRepoOwner:    owner,
+		UpstreamKrewIndexRepoCloneURL: getCloneURL(owner, repo),
+		LocalKrewIndexRepo:            forkRepo,
+		LocalKrewIndexRepoOwner:       forkOwner,
+		LocalKrewIndexRepoCloneURL:    getCloneURL(forkOwner, forkRepo),
+		BaseBranch:                    c.BaseBranch,
+		MirrorDir:                     releaser.MirrorDir,
+		GitBackend:                    releaser.GitBackend,
+		GithubAPIURL:                  releaser.GithubAPIURL,
+		PRTitleTemplate:               releaser.PRTitleTemplate,
+		PRBodyTemplate:                releaser.PRBodyTemplate,
+		RenderTemplates:               releaser.RenderTemplates,
+		tokenSource:                   releaser.tokenSource,
+	}
+
+	if c.TokenEnv != "" {
+		r.Token = os.Getenv(c.TokenEnv)
+		r.tokenSource = nil
+	}
+
+	return r
+}
@@ -0,0 +1,123 @@
+package releaser
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForIndex(t *testing.T) {
+	t.Setenv("KREW_RELEASE_BOT_CONFIG_FILE", "data/index-token-config.yaml")
+	t.Setenv("INTERNAL_KREW_INDEX_TOKEN", "internal-token")
+	config, err := GetConfig()
+	assert.Nil(t, err)
+
+	r := newTestReleaser().WithConfig(config)
+	r.MirrorDir = "/var/lib/krew-release-bot"
+	r.GitBackend = GitBackendAPI
+
+	testcases := []struct {
+		name               string
+		index              string
+		plugin             string
+		expectedUpstream   string
+		expectedFork       string
+		expectedCloneURL   string
+		expectedBaseBranch string
+		expectedToken      string
+		expectedError      string
+		expectedStatusCode int
+	}{
+		{
+			name:             "default index",
+			expectedUpstream: "kubernetes-sigs/krew-index",
+			expectedFork:     "krew-release-bot/krew-index",
+			expectedCloneURL: "https://github.com/krew-release-bot/krew-index.git",
+			expectedToken:    "token",
+		},
+		{
+			name:             "default index by name",
+			index:            "Kubernetes-Sigs/krew-index",
+			expectedUpstream: "kubernetes-sigs/krew-index",
+			expectedFork:     "krew-release-bot/krew-index",
+			expectedCloneURL: "https://github.com/krew-release-bot/krew-index.git",
+			expectedToken:    "token",
+		},
+		{
+			name:               "allowed index",
+			index:              "my-org/internal-krew-index",
+			expectedUpstream:   "my-org/internal-krew-index",
+			expectedFork:       "my-org-bot/internal-krew-index",
+			expectedCloneURL:   "https://github.com/my-org-bot/internal-krew-index.git",
+			expectedBaseBranch: "release",
+			expectedToken:      "internal-token",
+		},
+		{
+			name:               "index not allowed",
+			index:              "evil/krew-index",
+			expectedError:      `krew index "evil/krew-index" is not allowed`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:             "plugin owner allowed in index",
+			index:            "my-org/restricted-krew-index",
+			plugin:           "My-Org/kubectl-foo",
+			expectedUpstream: "my-org/restricted-krew-index",
+			expectedFork:     "krew-release-bot/restricted-krew-index",
+			expectedCloneURL: "https://github.com/krew-release-bot/restricted-krew-index.git",
+			expectedToken:    "token",
+		},
+		{
+			name:             "plugin repo allowed in index",
+			index:            "my-org/restricted-krew-index",
+			plugin:           "foo-bar/my-awesome-plugin",
+			expectedUpstream: "my-org/restricted-krew-index",
+			expectedFork:     "krew-release-bot/restricted-krew-index",
+			expectedCloneURL: "https://github.com/krew-release-bot/restricted-krew-index.git",
+			expectedToken:    "token",
+		},
+		{
+			name:               "plugin not allowed in index",
+			index:              "my-org/restricted-krew-index",
+			plugin:             "foo-bar/other-plugin",
+			expectedError:      `plugin repo foo-bar/other-plugin is not allowed in krew index "my-org/restricted-krew-index"`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin := tc.plugin
+			if plugin == "" {
+				plugin = "foo-bar/my-awesome-plugin"
+			}
+
+			owner, repo, _ := strings.Cut(plugin, "/")
+			request := &source.ReleaseRequest{Index: tc.index, PluginOwner: owner, PluginRepo: repo}
+
+			indexReleaser, err := r.forIndex(request)
+			assertError(t, tc.expectedError, err)
+			if tc.expectedError != "" {
+				assert.Equal(t, tc.expectedStatusCode, statusCodeFor(err))
+				return
+			}
+
+			assert.Equal(t, tc.expectedUpstream, indexReleaser.UpstreamKrewIndexRepoOwner+"/"+indexReleaser.UpstreamKrewIndexRepo)
+			assert.Equal(t, tc.expectedFork, indexReleaser.LocalKrewIndexRepoOwner+"/"+indexReleaser.LocalKrewIndexRepo)
+			assert.Equal(t, tc.expectedCloneURL, indexReleaser.LocalKrewIndexRepoCloneURL)
+			assert.Equal(t, tc.expectedBaseBranch, indexReleaser.BaseBranch)
+			assert.Equal(t, tc.expectedToken, indexReleaser.Token)
+			assert.Equal(t, "/var/lib/krew-release-bot", indexReleaser.MirrorDir)
+			assert.Equal(t, GitBackendAPI, indexReleaser.GitBackend)
+			assert.Equal(t, "krew-release-bot", indexReleaser.TokenUserHandle)
+
+			// the releaser for index is reused across requests
+			again, err := r.forIndex(request)
+			assert.Nil(t, err)
+			assert.Same(t, indexReleaser, again)
+		})
+	}
+}
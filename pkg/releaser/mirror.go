@@ -0,0 +1,148 @@
+package releaser
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/sirupsen/logrus"
+	ugit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// checkoutIndex checks out the branch for release request from the base branch of upstream
+// krew-index repo. It returns the directory of the worktree, along with the func to call
+// once done with it. If MirrorDir is set, the persistent mirror is used, otherwise the
+// repo is shallow cloned in a temp dir
+func (r *Releaser) checkoutIndex(request *source.ReleaseRequest, baseBranch string) (string, *ugit.Repository, func(), error) {
+	if r.MirrorDir == "" {
+		tempdir, err := os.MkdirTemp("", "krew-index-")
+		if err != nil {
+			return "", nil, nil, err
+		}
+
+		logrus.Infof("will operate in tempdir %s", tempdir)
+		repo, err := r.cloneRepos(tempdir, request, baseBranch)
+		if err != nil {
+			os.RemoveAll(tempdir)
+			return "", nil, nil, err
+		}
+
+		return tempdir, repo, func() { os.RemoveAll(tempdir) }, nil
+	}
+
+	// the worktree of mirror is shared by all requests
+	r.mirrorLock.Lock()
+	dir := filepath.Join(r.MirrorDir, r.UpstreamKrewIndexRepoOwner, r.UpstreamKrewIndexRepo)
+	logrus.Infof("will operate in mirror %s", dir)
+	repo, err := r.syncMirror(dir, request, baseBranch)
+	if err != nil {
+		r.mirrorLock.Unlock()
+		return "", nil, nil, err
+	}
+
+	return dir, repo, r.mirrorLock.Unlock, nil
+}
+
+// syncMirror fetches the base branch of upstream krew-index repo into the mirror, and
+// resets the worktree to a new branch for release request. The mirror is cloned fully
+// when it does not exist yet, as go-git does not support fetching into shallow clones
+func (r *Releaser) syncMirror(dir string, request *source.ReleaseRequest, baseBranch string) (*ugit.Repository, error) {
+	repo, err := ugit.PlainOpen(dir)
+	if err == ugit.ErrRepositoryNotExists {
+		repo, err = r.cloneUpstream(dir, baseBranch, 0)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.setForkRemote(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := r.getAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	remoteRef := plumbing.NewRemoteReferenceName(OriginNameUpstream, baseBranch)
+	err = repo.Fetch(&ugit.FetchOptions{
+		RemoteName: OriginNameUpstream,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + plumbing.NewBranchReferenceName(baseBranch).String() + ":" + remoteRef.String())},
+		Force:      true,
+		Auth:       auth,
+	})
+	if err != nil && err != ugit.NoErrAlreadyUpToDate {
+		return nil, err
+	}
+
+	logrus.Infof("fetched %s at branch %s in %s", r.UpstreamKrewIndexRepoCloneURL, baseBranch, time.Since(start))
+
+	ref, err := repo.Reference(remoteRef, true)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	// the branch may exist from an earlier request for the same release
+	branchName := plumbing.NewBranchReferenceName(*r.getBranchName(request))
+	err = repo.Storer.RemoveReference(branchName)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("creating branch %s at %s", branchName.Short(), ref.Hash())
+	err = w.Checkout(&ugit.CheckoutOptions{
+		Hash:   ref.Hash(),
+		Branch: branchName,
+		Create: true,
+		Force:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Clean(&ugit.CleanOptions{Dir: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// setForkRemote points the remote for pushing the changes to the current fork of krew-index.
+// the mirror persists across restarts, during which the fork may change in the config
+func (r *Releaser) setForkRemote(repo *ugit.Repository) error {
+	remote, err := repo.Remote(OriginNameLocal)
+	if err == nil && len(remote.Config().URLs) == 1 && remote.Config().URLs[0] == r.LocalKrewIndexRepoCloneURL {
+		return nil
+	}
+
+	if err != nil && err != ugit.ErrRemoteNotFound {
+		return err
+	}
+
+	if err == nil {
+		logrus.Infof("fork of mirror changed, removing remote %s at %v", OriginNameLocal, remote.Config().URLs)
+		err = repo.DeleteRemote(OriginNameLocal)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("Adding remote %s at %s", OriginNameLocal, r.LocalKrewIndexRepoCloneURL)
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: OriginNameLocal,
+		URLs: []string{r.LocalKrewIndexRepoCloneURL},
+	})
+	return err
+}
@@ -0,0 +1,213 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/krew"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/mod/semver"
+)
+
+// listPRs lists the PR's in upstream krew-index repo with given state.
+// if head is not empty, only the PR's from that head are listed
+func (r *Releaser) listPRs(client *github.Client, state, head string) ([]*github.PullRequest, error) {
+	opts := &github.PullRequestListOptions{
+		State:       state,
+		Head:        head,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	all := []*github.PullRequest{}
+	for {
+		prs, resp, err := client.PullRequests.List(context.TODO(), r.UpstreamKrewIndexRepoOwner, r.UpstreamKrewIndexRepo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, prs...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// findOpenPR returns the open PR for the branch of release request, if any
+func (r *Releaser) findOpenPR(client *github.Client, request *source.ReleaseRequest) (*github.PullRequest, error) {
+	prs, err := r.listPRs(client, "open", *r.getHead(request))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	return prs[0], nil
+}
+
+// findMergedPR returns the merged PR for the branch of release request, if any
+func (r *Releaser) findMergedPR(client *github.Client, request *source.ReleaseRequest) (*github.PullRequest, error) {
+	prs, err := r.listPRs(client, "closed", *r.getHead(request))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pr := range prs {
+		if pr.MergedAt != nil {
+			return pr, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// isManifestOnBranch returns true if the branch of release request
+// in the fork already has the plugin manifest of release request
+func (r *Releaser) isManifestOnBranch(client *github.Client, request *source.ReleaseRequest) (bool, error) {
+	content, _, resp, err := client.Repositories.GetContents(
+		context.TODO(),
+		r.LocalKrewIndexRepoOwner,
+		r.LocalKrewIndexRepo,
+		path.Join("plugins", krew.PluginFileName(request.PluginName)),
+		&github.RepositoryContentGetOptions{Ref: *r.getBranchName(request)},
+	)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if content == nil {
+		return false, nil
+	}
+
+	data, err := content.GetContent()
+	if err != nil {
+		return false, err
+	}
+
+	return data == string(request.ProcessedTemplate), nil
+}
+
+// isSamePlugin returns true if the PR was opened by the bot for releasing
+// another version of the plugin in release request. The title of PR is
+// compared with the title rendered for the release of its branch
+func (r *Releaser) isSamePlugin(pr *github.PullRequest, request *source.ReleaseRequest) bool {
+	prefix := fmt.Sprintf("%s:%s-%s-%s-", r.LocalKrewIndexRepoOwner, request.PluginOwner, request.PluginName, request.PluginRepo)
+	if !strings.HasPrefix(pr.GetHead().GetLabel(), prefix) {
+		return false
+	}
+
+	older := *request
+	older.TagName = strings.TrimPrefix(pr.GetHead().GetLabel(), prefix)
+	title, err := r.getTitle(&older)
+	return err == nil && pr.GetTitle() == *title
+}
+
+// isOlderRelease returns true if tag is semver lower than the tag of release request.
+// tags that are not valid semver are never considered older
+func isOlderRelease(tag, than string) bool {
+	canonical := func(v string) string {
+		if !strings.HasPrefix(v, "v") {
+			return "v" + v
+		}
+
+		return v
+	}
+
+	tag, than = canonical(tag), canonical(than)
+	return semver.IsValid(tag) && semver.IsValid(than) && semver.Compare(tag, than) < 0
+}
+
+// listReleaseBranches lists the branches of the plugin in the fork of krew-index, which
+// were pushed for other releases of the plugin. returns the map of branch name to its tag
+func (r *Releaser) listReleaseBranches(client *github.Client, request *source.ReleaseRequest) (map[string]string, error) {
+	prefix := fmt.Sprintf("%s-%s-%s-", request.PluginOwner, request.PluginName, request.PluginRepo)
+	opts := &github.ReferenceListOptions{
+		Ref:         "heads/" + prefix,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	branches := map[string]string{}
+	for {
+		refs, resp, err := client.Git.ListMatchingRefs(context.TODO(), r.LocalKrewIndexRepoOwner, r.LocalKrewIndexRepo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range refs {
+			branch := strings.TrimPrefix(ref.GetRef(), "refs/heads/")
+			branches[branch] = strings.TrimPrefix(branch, prefix)
+		}
+
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
+
+// supersedePRs closes the open PR's of older releases of the plugin, with a comment
+// pointing to the PR that supersedes them. PR's of newer releases are left open
+func (r *Releaser) supersedePRs(client *github.Client, request *source.ReleaseRequest, pr *github.PullRequest) error {
+	branches, err := r.listReleaseBranches(client, request)
+	if err != nil {
+		return err
+	}
+
+	prs := []*github.PullRequest{}
+	for branch, tag := range branches {
+		if !isOlderRelease(tag, request.TagName) {
+			continue
+		}
+
+		open, err := r.listPRs(client, "open", r.LocalKrewIndexRepoOwner+":"+branch)
+		if err != nil {
+			return err
+		}
+
+		prs = append(prs, open...)
+	}
+
+	for _, old := range prs {
+		if old.GetNumber() == pr.GetNumber() || !r.isSamePlugin(old, request) {
+			continue
+		}
+
+		logrus.Infof("closing pr %q superseded by %q", old.GetHTMLURL(), pr.GetHTMLURL())
+		_, _, err := client.Issues.CreateComment(
+			context.TODO(),
+			r.UpstreamKrewIndexRepoOwner,
+			r.UpstreamKrewIndexRepo,
+			old.GetNumber(),
+			&github.IssueComment{Body: github.String(fmt.Sprintf("superseded by #%d", pr.GetNumber()))},
+		)
+		if err != nil {
+			return err
+		}
+
+		_, _, err = client.PullRequests.Edit(
+			context.TODO(),
+			r.UpstreamKrewIndexRepoOwner,
+			r.UpstreamKrewIndexRepo,
+			old.GetNumber(),
+			&github.PullRequest{State: github.String("closed")},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
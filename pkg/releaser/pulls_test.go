@@ -0,0 +1,310 @@
+package releaser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func newTestReleaser() *Releaser {
+	return &Releaser{
+		Token:                      "token",
+		TokenUserHandle:            "krew-release-bot",
+		UpstreamKrewIndexRepo:      "krew-index",
+		UpstreamKrewIndexRepoOwner: "kubernetes-sigs",
+		LocalKrewIndexRepo:         "krew-index",
+		LocalKrewIndexRepoOwner:    "krew-release-bot",
+	}
+}
+
+func pullRequest(number int, tag, pluginName string) map[string]interface{} {
+	return map[string]interface{}{
+		"number":   number,
+		"html_url": fmt.Sprintf("https://github.com/kubernetes-sigs/krew-index/pull/%d", number),
+		"title":    "release new version " + tag + " of " + pluginName,
+		"head": map[string]interface{}{
+			"label": "krew-release-bot:foo-bar-" + pluginName + "-my-awesome-plugin-" + tag,
+		},
+	}
+}
+
+// mockReleaseBranches mocks the branches of my-awesome-plugin in the fork for the tags
+func mockReleaseBranches(tags ...string) {
+	refs := []interface{}{}
+	for _, tag := range tags {
+		refs = append(refs, map[string]interface{}{
+			"ref": "refs/heads/foo-bar-my-awesome-plugin-my-awesome-plugin-" + tag,
+		})
+	}
+
+	gock.New("https://api.github.com").
+		Get("/repos/krew-release-bot/krew-index/git/matching-refs/heads/foo-bar-my-awesome-plugin-my-awesome-plugin-").
+		Reply(200).
+		JSON(refs)
+}
+
+func TestSubmitPR(t *testing.T) {
+	request := &source.ReleaseRequest{
+		TagName:            "v0.0.3",
+		PluginName:         "my-awesome-plugin",
+		PluginOwner:        "foo-bar",
+		PluginRepo:         "my-awesome-plugin",
+		PluginReleaseActor: "foo-bar",
+	}
+
+	testcases := []struct {
+		name           string
+		setupMocks     func()
+		expectedPR     string
+		expectedStatus Status
+		expectedError  string
+	}{
+		{
+			name: "new pr supersedes pr of older release",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3").
+					Reply(200).
+					JSON([]interface{}{})
+
+				gock.New("https://api.github.com").
+					Post("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchType("json").
+					BodyString(`{"title":"release new version v0.0.3 of my-awesome-plugin","head":"krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3","base":"main","body":".*"}`).
+					Reply(201).
+					JSON(pullRequest(3, "v0.0.3", "my-awesome-plugin"))
+
+				mockReleaseBranches("v0.0.1", "v0.0.3", "v0.0.4")
+
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("state", "open").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.1").
+					Reply(200).
+					JSON([]interface{}{pullRequest(1, "v0.0.1", "my-awesome-plugin")})
+
+				gock.New("https://api.github.com").
+					Post("/repos/kubernetes-sigs/krew-index/issues/1/comments").
+					BodyString(`{"body":"superseded by #3"}`).
+					Reply(201).
+					JSON(map[string]interface{}{})
+
+				gock.New("https://api.github.com").
+					Patch("/repos/kubernetes-sigs/krew-index/pulls/1").
+					BodyString(`{"state":"closed"}`).
+					Reply(200).
+					JSON(pullRequest(1, "v0.0.1", "my-awesome-plugin"))
+			},
+			expectedPR:     "https://github.com/kubernetes-sigs/krew-index/pull/3",
+			expectedStatus: StatusCreated,
+		},
+		{
+			name: "superseding pr of older release fails",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3").
+					Reply(200).
+					JSON([]interface{}{})
+
+				gock.New("https://api.github.com").
+					Post("/repos/kubernetes-sigs/krew-index/pulls").
+					Reply(201).
+					JSON(pullRequest(3, "v0.0.3", "my-awesome-plugin"))
+
+				mockReleaseBranches("v0.0.1", "v0.0.3")
+
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("state", "open").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.1").
+					Reply(200).
+					JSON([]interface{}{pullRequest(1, "v0.0.1", "my-awesome-plugin")})
+
+				gock.New("https://api.github.com").
+					Post("/repos/kubernetes-sigs/krew-index/issues/1/comments").
+					Reply(500).
+					JSON(map[string]string{"message": "Server Error"})
+			},
+			expectedPR:     "https://github.com/kubernetes-sigs/krew-index/pull/3",
+			expectedStatus: StatusCreated,
+		},
+		{
+			name: "pr already open for the branch",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3").
+					Reply(200).
+					JSON([]interface{}{pullRequest(3, "v0.0.3", "my-awesome-plugin")})
+
+				mockReleaseBranches("v0.0.3")
+			},
+			expectedPR:     "https://github.com/kubernetes-sigs/krew-index/pull/3",
+			expectedStatus: StatusUpdated,
+		},
+		{
+			name: "pr of newer release is not superseded",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3").
+					Reply(200).
+					JSON([]interface{}{})
+
+				gock.New("https://api.github.com").
+					Post("/repos/kubernetes-sigs/krew-index/pulls").
+					Reply(201).
+					JSON(pullRequest(3, "v0.0.3", "my-awesome-plugin"))
+
+				mockReleaseBranches("v0.0.3", "v0.0.10", "not-semver")
+			},
+			expectedPR:     "https://github.com/kubernetes-sigs/krew-index/pull/3",
+			expectedStatus: StatusCreated,
+		},
+		{
+			name: "creating pr fails",
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/kubernetes-sigs/krew-index/pulls").
+					MatchParam("head", "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3").
+					Reply(200).
+					JSON([]interface{}{})
+
+				gock.New("https://api.github.com").
+					Post("/repos/kubernetes-sigs/krew-index/pulls").
+					Reply(422).
+					JSON(map[string]string{"message": "Validation Failed"})
+			},
+			expectedError: "POST https://api.github.com/repos/kubernetes-sigs/krew-index/pulls: 422 Validation Failed []",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			tc.setupMocks()
+
+			r := newTestReleaser()
+			result, err := r.submitPR(r.getGithubClient(), request, "main")
+			assertError(t, tc.expectedError, err)
+			if tc.expectedError == "" {
+				assert.Equal(t, tc.expectedPR, result.PR)
+				assert.Equal(t, tc.expectedStatus, result.Status)
+			}
+			assert.True(t, gock.IsDone())
+		})
+	}
+}
+
+func TestFindExistingRelease(t *testing.T) {
+	manifest := "apiVersion: krew.googlecontainertools.github.com/v1alpha2\nkind: Plugin\n"
+	request := &source.ReleaseRequest{
+		TagName:           "v0.0.3",
+		PluginName:        "my-awesome-plugin",
+		PluginOwner:       "foo-bar",
+		PluginRepo:        "my-awesome-plugin",
+		ProcessedTemplate: []byte(manifest),
+	}
+
+	head := "krew-release-bot:foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3"
+	mockPRs := func(state string, prs ...interface{}) {
+		gock.New("https://api.github.com").
+			Get("/repos/kubernetes-sigs/krew-index/pulls").
+			MatchParam("state", state).
+			MatchParam("head", head).
+			Reply(200).
+			JSON(prs)
+	}
+
+	mockBranchManifest := func(content string) {
+		gock.New("https://api.github.com").
+			Get("/repos/krew-release-bot/krew-index/contents/plugins/my-awesome-plugin.yaml").
+			MatchParam("ref", "foo-bar-my-awesome-plugin-my-awesome-plugin-v0.0.3").
+			Reply(200).
+			JSON(map[string]string{
+				"type":     "file",
+				"encoding": "base64",
+				"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+			})
+	}
+
+	merged := pullRequest(3, "v0.0.3", "my-awesome-plugin")
+	merged["merged_at"] = "2024-01-01T00:00:00Z"
+	closed := pullRequest(2, "v0.0.3", "my-awesome-plugin")
+
+	testcases := []struct {
+		name           string
+		setupMocks     func()
+		expectedResult *Result
+	}{
+		{
+			name: "pr already merged",
+			setupMocks: func() {
+				mockPRs("closed", closed, merged)
+			},
+			expectedResult: &Result{
+				Status:  StatusAlreadyMerged,
+				PR:      "https://github.com/kubernetes-sigs/krew-index/pull/3",
+				Message: `PR "https://github.com/kubernetes-sigs/krew-index/pull/3" for this release is already merged`,
+			},
+		},
+		{
+			name: "pr already open with same manifest",
+			setupMocks: func() {
+				mockPRs("closed", closed)
+				mockPRs("open", pullRequest(3, "v0.0.3", "my-awesome-plugin"))
+				mockBranchManifest(manifest)
+			},
+			expectedResult: &Result{
+				Status:  StatusAlreadyOpen,
+				PR:      "https://github.com/kubernetes-sigs/krew-index/pull/3",
+				Message: `PR "https://github.com/kubernetes-sigs/krew-index/pull/3" is already open for this release`,
+			},
+		},
+		{
+			name: "pr open with different manifest",
+			setupMocks: func() {
+				mockPRs("closed")
+				mockPRs("open", pullRequest(3, "v0.0.3", "my-awesome-plugin"))
+				mockBranchManifest("old manifest")
+			},
+		},
+		{
+			name: "branch deleted from fork",
+			setupMocks: func() {
+				mockPRs("closed")
+				mockPRs("open", pullRequest(3, "v0.0.3", "my-awesome-plugin"))
+				gock.New("https://api.github.com").
+					Get("/repos/krew-release-bot/krew-index/contents/plugins/my-awesome-plugin.yaml").
+					Reply(404).
+					JSON(map[string]string{"message": "Not Found"})
+			},
+		},
+		{
+			name: "no pr for the release",
+			setupMocks: func() {
+				mockPRs("closed")
+				mockPRs("open")
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			tc.setupMocks()
+
+			r := newTestReleaser()
+			result, err := r.findExistingRelease(r.getGithubClient(), request)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedResult, result)
+			assert.True(t, gock.IsDone())
+		})
+	}
+}
@@ -2,15 +2,69 @@ package releaser
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
+	"sync"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/pkg/errors"
 	"github.com/rajatjindal/krew-release-bot/pkg/krew"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
 	"github.com/rajatjindal/krew-release-bot/pkg/source/actions"
+	"github.com/rajatjindal/krew-release-bot/pkg/source/release"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
 )
 
+// Status is the outcome of the release request
+type Status string
+
+const (
+	// StatusCreated is when a new PR is opened for the release
+	StatusCreated Status = "created"
+
+	// StatusUpdated is when the open PR for the release is updated with the new manifest
+	StatusUpdated Status = "updated"
+
+	// StatusAlreadyOpen is when the PR for the release is already open with the same manifest
+	StatusAlreadyOpen Status = "already-open"
+
+	// StatusAlreadyMerged is when the PR for the release is already merged
+	StatusAlreadyMerged Status = "already-merged"
+
+	// StatusNoChange is when the manifest in krew-index is already up to date with the release
+	StatusNoChange Status = "no-change"
+)
+
+// Result is the result of the release request
+type Result struct {
+	Status  Status `json:"status"`
+	PR      string `json:"pr,omitempty"`
+	Message string `json:"message"`
+}
+
+func newResult(status Status, pr string) *Result {
+	messages := map[Status]string{
+		StatusCreated:       "PR %q submitted successfully",
+		StatusUpdated:       "PR %q updated successfully",
+		StatusAlreadyOpen:   "PR %q is already open for this release",
+		StatusAlreadyMerged: "PR %q for this release is already merged",
+	}
+
+	message := "plugin manifest in krew-index is already up to date with this release"
+	if format, ok := messages[status]; ok {
+		message = fmt.Sprintf(format, pr)
+	}
+
+	return &Result{
+		Status:  status,
+		PR:      pr,
+		Message: message,
+	}
+}
+
 // Releaser is what opens PR
 type Releaser struct {
 	Token                         string
@@ -23,38 +77,113 @@ type Releaser struct {
 	LocalKrewIndexRepo            string
 	LocalKrewIndexRepoOwner       string
 	LocalKrewIndexRepoCloneURL    string
+
+	// BaseBranch is the branch of upstream krew-index repo against which
+	// the PR's are opened. defaults to the default branch of the repo
+	BaseBranch string
+
+	// MirrorDir is the directory for persistent mirrors of krew-index repo, which are
+	// fetched for each request. if empty, the repo is shallow cloned for each request
+	MirrorDir string
+
+	// mirrorLock serializes the requests using the mirror
+	mirrorLock sync.Mutex
+
+	// GitBackend is how the plugin manifest is committed to the fork of krew-index
+	// repo, GitBackendClone (default) or GitBackendAPI. see GetGitBackend
+	GitBackend string
+
+	// GithubAPIURL is the url of github api, defaults to https://api.github.com/
+	GithubAPIURL string
+
+	// tokenSource returns the token for github api and git push, when
+	// authenticating as github app. Token is used if not set
+	tokenSource oauth2.TokenSource
+
+	// UpstreamToken is the token for PR's on upstream krew-index repo, when authenticating
+	// as github app that is not installed on it. the credentials of the bot are used if not set
+	UpstreamToken string
+
+	// PRTitleTemplate and PRBodyTemplate are the go templates for title and body of
+	// the PR, executed with the release request. default to that of public bot
+	PRTitleTemplate string
+	PRBodyTemplate  string
+
+	// Indexes are the custom krew indexes to which plugins can be released, in addition
+	// to the upstream krew-index repo. indexReleasers caches the releaser for each
+	Indexes        []IndexConfig
+	indexReleasers map[string]*Releaser
+	indexLock      sync.Mutex
+
+	// RenderTemplates renders the template from the plugin repo,
+	// instead of trusting the manifest rendered by the client
+	RenderTemplates bool
+
+	// background tracks the releases from github release webhook, which
+	// are processed after responding to the webhook delivery
+	background sync.WaitGroup
+
+	// actionHook is the handler for github action webhook. it is created once,
+	// so that the webhook secrets file is not read again for every request
+	actionHook     *actions.GithubActions
+	actionHookErr  error
+	actionHookOnce sync.Once
 }
 
 func getCloneURL(owner, repo string) string {
 	return fmt.Sprintf("https://github.com/%s/%s.git", owner, repo)
 }
 
-// TODO: get email, userhandle, name from token
-func getUserDetails(_ string) (string, string, string) {
-	return "krew-release-bot", "Krew Release Bot", "krewpluginreleasebot@gmail.com"
+// GetGitBackend returns the git backend from KREW_RELEASE_BOT_GIT_BACKEND env, defaults to GitBackendClone
+func GetGitBackend() string {
+	if os.Getenv("KREW_RELEASE_BOT_GIT_BACKEND") != "" {
+		return os.Getenv("KREW_RELEASE_BOT_GIT_BACKEND")
+	}
+
+	return GitBackendClone
 }
 
 // New returns new releaser object
 func New(ghToken string) *Releaser {
-	tokenUserHandle, tokenUsername, tokenEmail := getUserDetails(ghToken)
-
-	return &Releaser{
+	releaser := &Releaser{
 		Token:                         ghToken,
-		TokenEmail:                    tokenEmail,
-		TokenUserHandle:               tokenUserHandle,
-		TokenUsername:                 tokenUsername,
 		UpstreamKrewIndexRepo:         krew.GetKrewIndexRepoName(),
 		UpstreamKrewIndexRepoOwner:    krew.GetKrewIndexRepoOwner(),
 		UpstreamKrewIndexRepoCloneURL: getCloneURL(krew.GetKrewIndexRepoOwner(), krew.GetKrewIndexRepoName()),
 		LocalKrewIndexRepo:            krew.GetKrewIndexRepoName(),
-		LocalKrewIndexRepoOwner:       tokenUserHandle,
-		LocalKrewIndexRepoCloneURL:    "https://github.com/krew-release-bot/krew-index.git",
+		BaseBranch:                    krew.GetKrewIndexBaseBranch(),
+		RenderTemplates:               os.Getenv("KREW_RELEASE_BOT_RENDER_TEMPLATES") == "true",
 	}
+
+	return releaser.WithConfig(DefaultConfig())
+}
+
+// WithActionHook creates the handler for github action webhook, loading the webhook secrets
+// file. It is created on the first request otherwise, but this fails early for invalid file
+func (releaser *Releaser) WithActionHook() (*Releaser, error) {
+	_, err := releaser.getActionHook()
+	if err != nil {
+		return nil, err
+	}
+
+	return releaser, nil
+}
+
+func (releaser *Releaser) getActionHook() (*actions.GithubActions, error) {
+	releaser.actionHookOnce.Do(func() {
+		releaser.actionHook, releaser.actionHookErr = actions.NewGithubActions()
+		if releaser.actionHookErr == nil {
+			// the credentials of the bot are used to look up tags of plugin repos
+			releaser.actionHook.GithubClient = releaser.getGithubClient()
+		}
+	})
+
+	return releaser.actionHook, releaser.actionHookErr
 }
 
 // HandleActionLambdaWebhook handles requests from github actions
 func (releaser *Releaser) HandleActionLambdaWebhook(ctx context.Context, request events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
-	hook, err := actions.NewGithubActions()
+	hook, err := releaser.getActionHook()
 	if err != nil {
 		return &events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -65,28 +194,39 @@ func (releaser *Releaser) HandleActionLambdaWebhook(ctx context.Context, request
 	releaseRequest, err := hook.ParseLambdaRequest(request)
 	if err != nil {
 		return &events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
+			StatusCode: statusCodeFor(err),
 			Body:       errors.Wrap(err, "getting release request").Error(),
 		}, nil
 	}
 
-	pr, err := releaser.Release(releaseRequest)
+	result, err := releaser.Release(releaseRequest)
+	if err != nil {
+		statusCode, contentType, body := errorResponse(errors.Wrap(err, "opening pr"))
+		return &events.APIGatewayProxyResponse{
+			StatusCode: statusCode,
+			Headers:    map[string]string{"Content-Type": contentType},
+			Body:       body,
+		}, nil
+	}
+
+	body, err := json.Marshal(result)
 	if err != nil {
 		return &events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
-			Body:       errors.Wrap(err, "opening pr").Error(),
+			Body:       errors.Wrap(err, "encoding result").Error(),
 		}, nil
 	}
 
 	return &events.APIGatewayProxyResponse{
 		StatusCode: http.StatusOK,
-		Body:       fmt.Sprintf("PR %q submitted successfully", pr),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
 	}, nil
 }
 
 // HandleActionWebhook handles requests from github actions
 func (releaser *Releaser) HandleActionWebhook(w http.ResponseWriter, r *http.Request) {
-	hook, err := actions.NewGithubActions()
+	hook, err := releaser.getActionHook()
 	if err != nil {
 		http.Error(w, errors.Wrap(err, "creating instance of action handler").Error(), http.StatusInternalServerError)
 		return
@@ -94,16 +234,133 @@ func (releaser *Releaser) HandleActionWebhook(w http.ResponseWriter, r *http.Req
 
 	releaseRequest, err := hook.Parse(r)
 	if err != nil {
-		http.Error(w, errors.Wrap(err, "getting release request").Error(), http.StatusInternalServerError)
+		http.Error(w, errors.Wrap(err, "getting release request").Error(), statusCodeFor(err))
+		return
+	}
+
+	result, err := releaser.Release(releaseRequest)
+	if err != nil {
+		statusCode, contentType, body := errorResponse(errors.Wrap(err, "opening pr"))
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(statusCode)
+		_, _ = fmt.Fprintln(w, body)
+		return
+	}
+
+	writeResult(w, result)
+}
+
+// HandleReleaseWebhook handles the github release webhook events
+func (releaser *Releaser) HandleReleaseWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := release.NewGithubRelease()
+	if err != nil {
+		http.Error(w, errors.Wrap(err, "creating instance of release handler").Error(), http.StatusInternalServerError)
+		return
+	}
+
+	releaseRequest, err := hook.Parse(r)
+	var ignoredErr *release.IgnoredEventError
+	if errors.As(err, &ignoredErr) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(ignoredErr.Error()))
 		return
 	}
 
-	pr, err := releaser.Release(releaseRequest)
 	if err != nil {
-		http.Error(w, errors.Wrap(err, "opening pr").Error(), http.StatusInternalServerError)
+		http.Error(w, errors.Wrap(err, "getting release request").Error(), statusCodeFor(err))
 		return
 	}
 
+	// github times out the webhook delivery after 10s, so the release is
+	// processed in background after acknowledging the delivery
+	releaser.background.Go(func() {
+		result, err := releaser.ReleaseFromTemplate(releaseRequest)
+		if err != nil {
+			logrus.Errorf("releasing %s of %s/%s failed. error: %v", releaseRequest.TagName, releaseRequest.PluginOwner, releaseRequest.PluginRepo, err)
+			return
+		}
+
+		logrus.Info(result.Message)
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = fmt.Fprintf(w, "release %s of %s/%s accepted", releaseRequest.TagName, releaseRequest.PluginOwner, releaseRequest.PluginRepo)
+}
+
+// Wait waits for the releases being processed in background to complete, or the context to be done
+func (releaser *Releaser) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		releaser.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeResult writes the result of release request as json
+func writeResult(w http.ResponseWriter, result *Result) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, errors.Wrap(err, "encoding result").Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(fmt.Sprintf("PR %q submitted successfully", pr)))
+	_, _ = w.Write(body)
+}
+
+// statusCodeFor returns the http status code for the error from handling the release request
+func statusCodeFor(err error) int {
+	var authErr *source.AuthError
+	if errors.As(err, &authErr) {
+		return authErr.StatusCode
+	}
+
+	var verificationErr *VerificationError
+	if errors.As(err, &verificationErr) {
+		return http.StatusForbidden
+	}
+
+	var assetErr *source.AssetError
+	if errors.As(err, &assetErr) {
+		return http.StatusBadGateway
+	}
+
+	return http.StatusInternalServerError
+}
+
+// assetErrorResponse is the body of error response when processing a release asset fails
+type assetErrorResponse struct {
+	Error      string `json:"error"`
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+}
+
+// errorResponse returns the status code, content type and body of error response for the
+// release request. Errors of release assets are returned as json with the url of the asset,
+// status code of its download and attempts made, so the client can report the failed asset
+func errorResponse(err error) (int, string, string) {
+	var assetErr *source.AssetError
+	if errors.As(err, &assetErr) {
+		body, jsonErr := json.Marshal(&assetErrorResponse{
+			Error:      err.Error(),
+			URL:        assetErr.URL,
+			StatusCode: assetErr.StatusCode,
+			Attempts:   assetErr.Attempts,
+		})
+		if jsonErr == nil {
+			return statusCodeFor(err), "application/json", string(body)
+		}
+	}
+
+	return statusCodeFor(err), "text/plain; charset=utf-8", err.Error()
 }
@@ -0,0 +1,57 @@
+package releaser
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponse(t *testing.T) {
+	testcases := []struct {
+		name                string
+		err                 error
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name: "asset error",
+			err: errors.Wrap(&source.AssetError{
+				URL:        "https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz",
+				StatusCode: http.StatusNotFound,
+				Attempts:   4,
+				Err:        fmt.Errorf("downloading file failed. status code: 404, expected: 200"),
+			}, "opening pr"),
+			expectedStatusCode:  http.StatusBadGateway,
+			expectedContentType: "application/json",
+			expectedBody:        `{"error":"opening pr: asset https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz: downloading file failed. status code: 404, expected: 200","url":"https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz","statusCode":404,"attempts":4}`,
+		},
+		{
+			name:                "verification error",
+			err:                 errors.Wrap(&VerificationError{Err: fmt.Errorf(`plugin name is "other-plugin", expected "my-awesome-plugin"`)}, "opening pr"),
+			expectedStatusCode:  http.StatusForbidden,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        `opening pr: verifying submitted manifest failed. plugin name is "other-plugin", expected "my-awesome-plugin"`,
+		},
+		{
+			name:                "other error",
+			err:                 errors.Wrap(fmt.Errorf("pushing branch failed"), "opening pr"),
+			expectedStatusCode:  http.StatusInternalServerError,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "opening pr: pushing branch failed",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			statusCode, contentType, body := errorResponse(tc.err)
+			assert.Equal(t, tc.expectedStatusCode, statusCode)
+			assert.Equal(t, tc.expectedContentType, contentType)
+			assert.Equal(t, tc.expectedBody, body)
+		})
+	}
+}
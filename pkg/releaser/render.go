@@ -0,0 +1,271 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/krew"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// VerificationError is returned when the submitted manifest
+// does not match the one rendered by the bot
+type VerificationError struct {
+	Err error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verifying submitted manifest failed. %v", e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+func (r *Releaser) getTokenSource() oauth2.TokenSource {
+	if r.tokenSource != nil {
+		return r.tokenSource
+	}
+
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: r.Token})
+}
+
+// hasCredentials returns true if the token or github app is configured
+func (r *Releaser) hasCredentials() bool {
+	return r.Token != "" || r.tokenSource != nil
+}
+
+func (r *Releaser) getGithubClient() *github.Client {
+	return r.newGithubClient(oauth2.NewClient(context.TODO(), r.getTokenSource()))
+}
+
+// getUpstreamGithubClient returns the client for PR's on upstream krew-index repo. The
+// installation token of github app can only open PR's on the repos the app is installed on
+func (r *Releaser) getUpstreamGithubClient() *github.Client {
+	if r.UpstreamToken == "" {
+		return r.getGithubClient()
+	}
+
+	return r.newGithubClient(oauth2.NewClient(context.TODO(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: r.UpstreamToken})))
+}
+
+func (r *Releaser) newGithubClient(tc *http.Client) *github.Client {
+	client := github.NewClient(tc)
+	if r.GithubAPIURL != "" {
+		baseURL, err := url.Parse(strings.TrimSuffix(r.GithubAPIURL, "/") + "/")
+		if err == nil {
+			client.BaseURL = baseURL
+		}
+	}
+
+	return client
+}
+
+// templateFileInRepo returns the path of template file relative to root of plugin repo
+func templateFileInRepo(templateFile string) (string, error) {
+	if templateFile == "" {
+		return ".krew.yaml", nil
+	}
+
+	file := path.Clean(filepath.ToSlash(templateFile))
+	if path.IsAbs(file) || file == ".." || strings.HasPrefix(file, "../") {
+		return "", fmt.Errorf("template file %q is not relative to the plugin repo", templateFile)
+	}
+
+	return file, nil
+}
+
+// fetchTemplate fetches the template file from plugin repo at the release tag
+func (r *Releaser) fetchTemplate(request *source.ReleaseRequest) ([]byte, error) {
+	file, err := templateFileInRepo(request.TemplateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("fetching template %s from %s/%s at %s", file, request.PluginOwner, request.PluginRepo, request.TagName)
+	content, _, _, err := r.getGithubClient().Repositories.GetContents(
+		context.TODO(),
+		request.PluginOwner,
+		request.PluginRepo,
+		file,
+		&github.RepositoryContentGetOptions{Ref: fmt.Sprintf("refs/tags/%s", request.TagName)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if content == nil {
+		return nil, fmt.Errorf("template file %s in %s/%s is a directory", file, request.PluginOwner, request.PluginRepo)
+	}
+
+	data, err := content.GetContent()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(data), nil
+}
+
+// processTemplate renders the template from the plugin repo at the release tag
+func (r *Releaser) processTemplate(request *source.ReleaseRequest) (string, []byte, error) {
+	data, err := r.fetchTemplate(request)
+	if err != nil {
+		return "", nil, err
+	}
+
+	templateFile, err := os.CreateTemp("", "krew-template-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(templateFile.Name())
+
+	err = os.WriteFile(templateFile.Name(), data, 0644)
+	if err != nil {
+		return "", nil, err
+	}
+
+	values := &source.ReleaseRequest{
+		TagName:            request.TagName,
+		PluginOwner:        request.PluginOwner,
+		PluginRepo:         request.PluginRepo,
+		PluginReleaseActor: request.PluginReleaseActor,
+		TemplateFile:       request.TemplateFile,
+		Index:              request.Index,
+	}
+
+	// the template is untrusted, so the assets are checked before any of them is downloaded
+	uris, err := source.GetAssetURLs(templateFile.Name(), values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, uri := range uris {
+		err := verifyReleaseAssetURL(request, uri)
+		if err != nil {
+			return "", nil, &VerificationError{Err: err}
+		}
+	}
+
+	return source.ProcessTemplate(templateFile.Name(), values)
+}
+
+// renderTemplate renders the template from the plugin repo at the release tag, and
+// verifies the submitted manifest against it. The manifest rendered by the bot
+// replaces the submitted one, so only assets of the plugin release are published
+func (r *Releaser) renderTemplate(request *source.ReleaseRequest) error {
+	pluginName, spec, err := r.processTemplate(request)
+	if err != nil {
+		return err
+	}
+
+	if pluginName != request.PluginName {
+		return &VerificationError{Err: fmt.Errorf("plugin name is %q, expected %q", request.PluginName, pluginName)}
+	}
+
+	err = verifyAssets(request, spec)
+	if err != nil {
+		return &VerificationError{Err: err}
+	}
+
+	request.ProcessedTemplate = spec
+	return nil
+}
+
+// ReleaseFromTemplate renders the template from the plugin repo at the release tag,
+// and opens the PR with the rendered manifest. It is used for release requests that
+// are not rendered by the client, e.g. from github release webhook. The urls in the
+// template are verified to be assets of the plugin release before downloading them
+func (r *Releaser) ReleaseFromTemplate(request *source.ReleaseRequest) (*Result, error) {
+	indexReleaser, err := r.forIndex(request)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginName, spec, err := indexReleaser.processTemplate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyReleaseAssets(request, spec)
+	if err != nil {
+		return nil, &VerificationError{Err: err}
+	}
+
+	request.PluginName = pluginName
+	request.ProcessedTemplate = spec
+	return indexReleaser.release(request)
+}
+
+// verifyReleaseAssets verifies that the platforms in rendered spec only point to assets of the plugin release
+func verifyReleaseAssets(request *source.ReleaseRequest, spec []byte) error {
+	platforms, err := krew.GetPluginPlatforms(spec)
+	if err != nil {
+		return err
+	}
+
+	for _, platform := range platforms {
+		err := verifyReleaseAssetURL(request, platform.URI)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyReleaseAssetURL verifies that uri is an asset of the plugin release
+func verifyReleaseAssetURL(request *source.ReleaseRequest, uri string) error {
+	asset, ok := source.ParseGitHubReleaseAssetURL(uri)
+	if !ok ||
+		!strings.EqualFold(asset.Owner, request.PluginOwner) ||
+		!strings.EqualFold(asset.Repo, request.PluginRepo) ||
+		asset.Tag != request.TagName {
+		return fmt.Errorf("uri %s is not an asset of release %s of %s/%s", uri, request.TagName, request.PluginOwner, request.PluginRepo)
+	}
+
+	return nil
+}
+
+// verifyAssets verifies that the platforms in rendered spec only point to assets of the
+// plugin release, and that the submitted manifest has the same uri and sha256 for them
+func verifyAssets(request *source.ReleaseRequest, spec []byte) error {
+	err := verifyReleaseAssets(request, spec)
+	if err != nil {
+		return err
+	}
+
+	expected, err := krew.GetPluginPlatforms(spec)
+	if err != nil {
+		return err
+	}
+
+	submitted, err := krew.GetPluginPlatforms(request.ProcessedTemplate)
+	if err != nil {
+		return err
+	}
+
+	if len(submitted) != len(expected) {
+		return fmt.Errorf("submitted manifest has %d platforms, expected %d", len(submitted), len(expected))
+	}
+
+	for i := range expected {
+		if submitted[i].URI != expected[i].URI {
+			return fmt.Errorf("uri of platform %d is %q, expected %q", i, submitted[i].URI, expected[i].URI)
+		}
+
+		if submitted[i].Sha256 != expected[i].Sha256 {
+			return fmt.Errorf("sha256 of %s is %q, expected %q", expected[i].URI, submitted[i].Sha256, expected[i].Sha256)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package releaser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
+
+func TestTemplateFileInRepo(t *testing.T) {
+	testcases := []struct {
+		name          string
+		templateFile  string
+		expectedFile  string
+		expectedError string
+	}{
+		{
+			name:         "default template file",
+			templateFile: "",
+			expectedFile: ".krew.yaml",
+		},
+		{
+			name:         "template file in sub directory",
+			templateFile: "./templates/misc/../plugin.yaml",
+			expectedFile: "templates/plugin.yaml",
+		},
+		{
+			name:          "absolute path",
+			templateFile:  "/github/workspace/.krew.yaml",
+			expectedError: `template file "/github/workspace/.krew.yaml" is not relative to the plugin repo`,
+		},
+		{
+			name:          "path outside repo",
+			templateFile:  "../other-repo/.krew.yaml",
+			expectedError: `template file "../other-repo/.krew.yaml" is not relative to the plugin repo`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := templateFileInRepo(tc.templateFile)
+			assertError(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedFile, file)
+		})
+	}
+}
+
+func mockAssets() {
+	for _, platform := range []string{"darwin-amd64", "linux-amd64"} {
+		gock.New("https://github.com").
+			Get("/foo-bar/my-awesome-plugin/releases/download/v0.0.2/" + platform + "-v0.0.2.tar.gz").
+			Persist().
+			Reply(200).
+			BodyString(platform + "-binary")
+	}
+}
+
+func mockTemplate(t *testing.T, file string) {
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+
+	gock.New("https://api.github.com").
+		Get("/repos/foo-bar/my-awesome-plugin/contents/.krew.yaml").
+		MatchParam("ref", "refs/tags/v0.0.2").
+		Reply(200).
+		JSON(map[string]string{
+			"type":     "file",
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString(data),
+		})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	os.Unsetenv("GITHUB_TOKEN")
+	defer gock.Off()
+	mockAssets()
+
+	values := &source.ReleaseRequest{
+		TagName:     "v0.0.2",
+		PluginOwner: "foo-bar",
+		PluginRepo:  "my-awesome-plugin",
+	}
+	_, manifest, err := source.ProcessTemplate("data/.krew.yaml", values)
+	assert.Nil(t, err)
+
+	darwinSha := fmt.Sprintf("%x", sha256.Sum256([]byte("darwin-amd64-binary")))
+
+	testcases := []struct {
+		name              string
+		template          string
+		pluginName        string
+		processedTemplate []byte
+		expectedError     string
+	}{
+		{
+			name:              "submitted manifest matches",
+			template:          "data/.krew.yaml",
+			pluginName:        "my-awesome-plugin",
+			processedTemplate: manifest,
+		},
+		{
+			name:              "sha256 does not match",
+			template:          "data/.krew.yaml",
+			pluginName:        "my-awesome-plugin",
+			processedTemplate: []byte(strings.Replace(string(manifest), "sha256: ", "sha256: 0", 1)),
+			expectedError:     fmt.Sprintf(`verifying submitted manifest failed. sha256 of https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz is "0%s", expected "%s"`, darwinSha, darwinSha),
+		},
+		{
+			name:              "uri does not match",
+			template:          "data/.krew.yaml",
+			pluginName:        "my-awesome-plugin",
+			processedTemplate: []byte(strings.Replace(string(manifest), "https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/linux-amd64", "https://evil.example.com/linux-amd64", 1)),
+			expectedError:     `verifying submitted manifest failed. uri of platform 1 is "https://evil.example.com/linux-amd64-v0.0.2.tar.gz", expected "https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/linux-amd64-v0.0.2.tar.gz"`,
+		},
+		{
+			name:              "plugin name does not match",
+			template:          "data/.krew.yaml",
+			pluginName:        "other-plugin",
+			processedTemplate: manifest,
+			expectedError:     `verifying submitted manifest failed. plugin name is "other-plugin", expected "my-awesome-plugin"`,
+		},
+		{
+			name:              "asset outside of plugin release",
+			template:          "data/outside-repo.krew.yaml",
+			pluginName:        "my-awesome-plugin",
+			processedTemplate: manifest,
+			expectedError:     "verifying submitted manifest failed. uri https://evil.example.com/foo-bar/my-awesome-plugin/v0.0.2/linux-amd64-v0.0.2.tar.gz is not an asset of release v0.0.2 of foo-bar/my-awesome-plugin",
+		},
+		{
+			name:              "checksums file outside of plugin release",
+			template:          "data/outside-checksums.krew.yaml",
+			pluginName:        "my-awesome-plugin",
+			processedTemplate: manifest,
+			expectedError:     "verifying submitted manifest failed. uri http://169.254.169.254/latest/meta-data/checksums.txt is not an asset of release v0.0.2 of foo-bar/my-awesome-plugin",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTemplate(t, tc.template)
+
+			request := &source.ReleaseRequest{
+				TagName:           "v0.0.2",
+				PluginName:        tc.pluginName,
+				PluginOwner:       "foo-bar",
+				PluginRepo:        "my-awesome-plugin",
+				TemplateFile:      ".krew.yaml",
+				ProcessedTemplate: tc.processedTemplate,
+			}
+
+			releaser := &Releaser{Token: "token", RenderTemplates: true}
+			err := releaser.renderTemplate(request)
+			if tc.expectedError == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, string(manifest), string(request.ProcessedTemplate))
+				return
+			}
+
+			assertError(t, tc.expectedError, err)
+			var verificationErr *VerificationError
+			assert.True(t, errors.As(err, &verificationErr))
+		})
+	}
+}
+
+func TestReleaseFromTemplate(t *testing.T) {
+	os.Unsetenv("GITHUB_TOKEN")
+	defer gock.Off()
+
+	testcases := []struct {
+		name          string
+		index         string
+		template      string
+		expectedError string
+	}{
+		{
+			name:          "asset outside of plugin release",
+			template:      "data/outside-repo.krew.yaml",
+			expectedError: "verifying submitted manifest failed. uri https://evil.example.com/foo-bar/my-awesome-plugin/v0.0.2/linux-amd64-v0.0.2.tar.gz is not an asset of release v0.0.2 of foo-bar/my-awesome-plugin",
+		},
+		{
+			name:          "checksums file outside of plugin release",
+			template:      "data/outside-checksums.krew.yaml",
+			expectedError: "verifying submitted manifest failed. uri http://169.254.169.254/latest/meta-data/checksums.txt is not an asset of release v0.0.2 of foo-bar/my-awesome-plugin",
+		},
+		{
+			name:          "index not allowed",
+			index:         "my-org/krew-index",
+			expectedError: `krew index "my-org/krew-index" is not allowed`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			// assets are not mocked, as nothing is downloaded when
+			// the template refers to urls outside of the plugin release
+			if tc.template != "" {
+				mockTemplate(t, tc.template)
+			}
+
+			request := &source.ReleaseRequest{
+				TagName:      "v0.0.2",
+				PluginOwner:  "foo-bar",
+				PluginRepo:   "my-awesome-plugin",
+				TemplateFile: ".krew.yaml",
+				Index:        tc.index,
+			}
+
+			_, err := newTestReleaser().ReleaseFromTemplate(request)
+			assertError(t, tc.expectedError, err)
+		})
+	}
+}
+
+func TestGetUpstreamGithubClient(t *testing.T) {
+	testcases := []struct {
+		name                  string
+		upstreamToken         string
+		expectedAuthorization string
+	}{
+		{
+			name:                  "credentials of the bot",
+			expectedAuthorization: "Bearer token",
+		},
+		{
+			name:                  "upstream token",
+			upstreamToken:         "upstream-token",
+			expectedAuthorization: "Bearer upstream-token",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			gock.New("https://api.github.com").
+				Get("/repos/kubernetes-sigs/krew-index").
+				MatchHeader("Authorization", tc.expectedAuthorization).
+				Reply(200).
+				JSON(map[string]string{"default_branch": "master"})
+
+			r := newTestReleaser()
+			r.UpstreamToken = tc.upstreamToken
+			_, _, err := r.getUpstreamGithubClient().Repositories.Get(context.TODO(), "kubernetes-sigs", "krew-index")
+			assert.Nil(t, err)
+			assert.True(t, gock.IsDone())
+		})
+	}
+}
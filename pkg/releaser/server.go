@@ -0,0 +1,45 @@
+package releaser
+
+import (
+	"net/http"
+)
+
+// WithUpstreamKrewIndex sets the krew-index repo against which the PR's are opened
+func (releaser *Releaser) WithUpstreamKrewIndex(owner, repo string) *Releaser {
+	releaser.UpstreamKrewIndexRepoOwner = owner
+	releaser.UpstreamKrewIndexRepo = repo
+	releaser.UpstreamKrewIndexRepoCloneURL = getCloneURL(owner, repo)
+	releaser.LocalKrewIndexRepo = repo
+	releaser.LocalKrewIndexRepoCloneURL = getCloneURL(releaser.LocalKrewIndexRepoOwner, repo)
+	return releaser
+}
+
+// Handler returns the http handler serving the github action and release webhooks, along with
+// health and readiness checks. ready reports whether the server can accept requests
+func (releaser *Releaser) Handler(ready func() bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /github-action-webhook", releaser.HandleActionWebhook)
+	mux.HandleFunc("POST /github-release-webhook", releaser.HandleReleaseWebhook)
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !releaser.hasCredentials() {
+			http.Error(w, "github token not configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		if !ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	return mux
+}
@@ -0,0 +1,175 @@
+package releaser
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestHandler(t *testing.T) {
+	t.Setenv("KREW_RELEASE_BOT_REQUIRE_VERIFICATION", "true")
+
+	testcases := []struct {
+		name               string
+		token              string
+		ready              bool
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "healthz",
+			method:             http.MethodGet,
+			path:               "/healthz",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "ok",
+		},
+		{
+			name:               "readyz when ready",
+			token:              "token",
+			ready:              true,
+			method:             http.MethodGet,
+			path:               "/readyz",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "ok",
+		},
+		{
+			name:               "readyz when shutting down",
+			token:              "token",
+			ready:              false,
+			method:             http.MethodGet,
+			path:               "/readyz",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       "not ready\n",
+		},
+		{
+			name:               "readyz without token",
+			ready:              true,
+			method:             http.MethodGet,
+			path:               "/readyz",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       "github token not configured\n",
+		},
+		{
+			name:               "webhook only accepts post",
+			token:              "token",
+			ready:              true,
+			method:             http.MethodGet,
+			path:               "/github-action-webhook",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedBody:       "Method Not Allowed\n",
+		},
+		{
+			name:               "webhook rejects unverified requests",
+			token:              "token",
+			ready:              true,
+			method:             http.MethodPost,
+			path:               "/github-action-webhook",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "getting release request: missing oidc token or signature. add 'id-token: write' to the permissions of the workflow, or set input 'webhook_secret' when not using github actions\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			releaser := &Releaser{Token: tc.token}
+			handler := releaser.Handler(func() bool { return tc.ready })
+
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"tagName": "v0.0.2"}`))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedStatusCode, w.Code)
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandleReleaseWebhook(t *testing.T) {
+	t.Setenv("KREW_RELEASE_BOT_GITHUB_WEBHOOK_SECRET", "my-secret")
+	defer gock.Off()
+
+	// the template is fetched in background, after responding to the delivery
+	gock.New("https://api.github.com").
+		Get("/repos/foo-bar/my-awesome-plugin/contents/.krew.yaml").
+		Reply(404).
+		JSON(map[string]string{"message": "Not Found"})
+
+	body := `{"action": "published", "release": {"tag_name": "v0.0.2"}, "repository": {"name": "my-awesome-plugin", "owner": {"login": "foo-bar"}}}`
+	mac := hmac.New(sha256.New, []byte("my-secret"))
+	mac.Write([]byte(body))
+
+	r := httptest.NewRequest(http.MethodPost, "/github-release-webhook", strings.NewReader(body))
+	r.Header.Set("X-GitHub-Event", "release")
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+
+	releaser := newTestReleaser()
+	releaser.HandleReleaseWebhook(w, r)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "release v0.0.2 of foo-bar/my-awesome-plugin accepted", w.Body.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.Nil(t, releaser.Wait(ctx))
+	assert.True(t, gock.IsDone())
+}
+
+func TestWithActionHook(t *testing.T) {
+	t.Run("invalid secrets file", func(t *testing.T) {
+		t.Setenv("KREW_RELEASE_BOT_WEBHOOK_SECRETS_FILE", "data/not-found.yaml")
+		_, err := newTestReleaser().WithActionHook()
+		assertError(t, "open data/not-found.yaml: no such file or directory", err)
+	})
+
+	t.Run("secrets file is loaded once", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "secrets.yaml")
+		assert.Nil(t, os.WriteFile(file, []byte("repos:\n  foo-bar/my-awesome-plugin: my-secret\n"), 0600))
+		t.Setenv("KREW_RELEASE_BOT_WEBHOOK_SECRETS_FILE", file)
+
+		releaser, err := newTestReleaser().WithActionHook()
+		assert.Nil(t, err)
+
+		assert.Nil(t, os.Remove(file))
+		hook, err := releaser.getActionHook()
+		assert.Nil(t, err)
+		assert.NotNil(t, hook.Secrets)
+	})
+
+	t.Run("tags are looked up with the credentials of the bot", func(t *testing.T) {
+		defer gock.Off()
+		gock.New("https://api.github.com").
+			Get("/repos/foo-bar/my-awesome-plugin/git/ref/tags/v0.0.2").
+			MatchHeader("Authorization", "Bearer token").
+			Reply(200).
+			JSON(map[string]string{"ref": "refs/tags/v0.0.2"})
+
+		hook, err := newTestReleaser().getActionHook()
+		assert.Nil(t, err)
+
+		_, _, err = hook.GithubClient.Git.GetRef(context.TODO(), "foo-bar", "my-awesome-plugin", "tags/v0.0.2")
+		assert.Nil(t, err)
+		assert.True(t, gock.IsDone())
+	})
+}
+
+func TestWithUpstreamKrewIndex(t *testing.T) {
+	releaser := New("token").WithUpstreamKrewIndex("my-org", "internal-krew-index")
+
+	assert.Equal(t, "my-org", releaser.UpstreamKrewIndexRepoOwner)
+	assert.Equal(t, "internal-krew-index", releaser.UpstreamKrewIndexRepo)
+	assert.Equal(t, "https://github.com/my-org/internal-krew-index.git", releaser.UpstreamKrewIndexRepoCloneURL)
+	assert.Equal(t, "internal-krew-index", releaser.LocalKrewIndexRepo)
+}
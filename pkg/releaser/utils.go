@@ -1,71 +1,166 @@
 package releaser
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/google/go-github/v66/github"
 	"github.com/rajatjindal/krew-release-bot/pkg/krew"
 	"github.com/rajatjindal/krew-release-bot/pkg/source"
 	"github.com/sirupsen/logrus"
 )
 
-// Release releases
-func (releaser *Releaser) Release(request *source.ReleaseRequest) (string, error) {
-	tempdir, err := os.MkdirTemp("", "krew-index-")
+// Release releases the plugin to the krew index of release request
+func (releaser *Releaser) Release(request *source.ReleaseRequest) (*Result, error) {
+	r, err := releaser.forIndex(request)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer os.RemoveAll(tempdir)
 
-	logrus.Infof("will operate in tempdir %s", tempdir)
-	repo, err := releaser.cloneRepos(tempdir, request)
+	if r.RenderTemplates {
+		err := r.renderTemplate(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r.release(request)
+}
+
+// release opens the PR for the rendered plugin manifest in the release request. If the
+// release was already submitted, the existing PR is returned without pushing any changes
+func (releaser *Releaser) release(request *source.ReleaseRequest) (*Result, error) {
+	start := time.Now()
+	defer func() {
+		logrus.Infof("processed release %s of %s in %s", request.TagName, request.PluginName, time.Since(start))
+	}()
+
+	client := releaser.getGithubClient()
+	upstream := releaser.getUpstreamGithubClient()
+	result, err := releaser.findExistingRelease(upstream, request)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if result != nil {
+		logrus.Infof("release %s of %s already submitted. status: %s, pr: %q", request.TagName, request.PluginName, result.Status, result.PR)
+		return result, nil
+	}
+
+	baseBranch, err := releaser.getBaseBranch(client)
+	if err != nil {
+		return nil, err
 	}
 
 	newIndexFile, err := os.CreateTemp("", "krew-")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer os.Remove(newIndexFile.Name())
 
 	err = os.WriteFile(newIndexFile.Name(), request.ProcessedTemplate, 0644)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	existingIndexFile := filepath.Join(tempdir, "plugins", krew.PluginFileName(request.PluginName))
-	logrus.Info("update plugin manifest with latest release info")
 	err = krew.ValidatePlugin(request.PluginName, newIndexFile.Name())
 	if err != nil {
-		return "", fmt.Errorf("failed when validating plugin spec with error: %s", err.Error())
+		return nil, fmt.Errorf("failed when validating plugin spec with error: %s", err.Error())
 	}
 
-	_, err = copyFile(newIndexFile.Name(), existingIndexFile)
+	var changed bool
+	switch releaser.GitBackend {
+	case GitBackendAPI:
+		changed, err = releaser.commitWithAPI(client, request, baseBranch)
+	case GitBackendClone, "":
+		changed, err = releaser.commitWithClone(request, baseBranch, newIndexFile.Name())
+	default:
+		err = fmt.Errorf("unknown git backend %q, expected %q or %q", releaser.GitBackend, GitBackendClone, GitBackendAPI)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !changed {
+		logrus.Infof("plugin manifest of %s is already up to date with release %s", request.PluginName, request.TagName)
+		return newResult(StatusNoChange, ""), nil
+	}
+
+	logrus.Info("submitting the pr")
+	return releaser.submitPR(upstream, request, baseBranch)
+}
+
+// commitWithClone clones the krew-index repo, and pushes the plugin manifest to the branch of
+// release request in the fork. returns false if the manifest in base branch is already up to date
+func (releaser *Releaser) commitWithClone(request *source.ReleaseRequest, baseBranch, newIndexFile string) (bool, error) {
+	dir, repo, done, err := releaser.checkoutIndex(request, baseBranch)
 	if err != nil {
-		return "", fmt.Errorf("failed when copying plugin spec with error: %s", err.Error())
+		return false, err
+	}
+	defer done()
+
+	existingIndexFile := filepath.Join(dir, "plugins", krew.PluginFileName(request.PluginName))
+	existing, err := os.ReadFile(existingIndexFile)
+	if err == nil && bytes.Equal(existing, request.ProcessedTemplate) {
+		return false, nil
+	}
+
+	logrus.Info("update plugin manifest with latest release info")
+	_, err = copyFile(newIndexFile, existingIndexFile)
+	if err != nil {
+		return false, fmt.Errorf("failed when copying plugin spec with error: %s", err.Error())
 	}
 
 	logrus.Infof("pushing changes to branch %s", *releaser.getBranchName(request))
 	commit := commitConfig{
-		Msg:        fmt.Sprintf("new version %s of %s", request.TagName, request.PluginName),
+		Msg:        releaser.getCommitMessage(request),
 		RemoteName: OriginNameLocal,
 	}
 
 	err = releaser.addCommitAndPush(repo, commit, request)
 	if err != nil {
-		return "", err
+		return false, err
 	}
 
-	logrus.Info("submitting the pr")
-	pr, err := releaser.submitPR(request)
+	return true, nil
+}
+
+// findExistingRelease returns the result for release request that was already
+// submitted, i.e. its PR is merged, or is open with the same plugin manifest
+func (releaser *Releaser) findExistingRelease(client *github.Client, request *source.ReleaseRequest) (*Result, error) {
+	merged, err := releaser.findMergedPR(client, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if merged != nil {
+		return newResult(StatusAlreadyMerged, merged.GetHTMLURL()), nil
+	}
+
+	open, err := releaser.findOpenPR(client, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if open == nil {
+		return nil, nil
+	}
+
+	same, err := releaser.isManifestOnBranch(client, request)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if !same {
+		return nil, nil
 	}
 
-	return pr, nil
+	return newResult(StatusAlreadyOpen, open.GetHTMLURL()), nil
 }
 
 func copyFile(src, dst string) (int64, error) {
@@ -8,14 +8,24 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/rajatjindal/krew-release-bot/pkg/cicd"
+	"github.com/rajatjindal/krew-release-bot/pkg/cicd/github"
 	"github.com/rajatjindal/krew-release-bot/pkg/source"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 )
 
+const (
+	defaultWebhookURL = "https://krew-release-bot.rajatjindal.com/github-action-webhook"
+
+	// defaultWebhookTimeout is the timeout for submitting the release request to the bot
+	defaultWebhookTimeout = 15 * time.Minute
+)
+
 func getHTTPClient() *http.Client {
 	if os.Getenv("GITHUB_TOKEN") != "" {
 		logrus.Info("GITHUB_TOKEN env variable found, using authenticated requests.")
@@ -26,14 +36,19 @@ func getHTTPClient() *http.Client {
 	return nil
 }
 
-// RunAction runs the github action
-func RunAction() error {
-	provider := cicd.GetProvider()
-
-	if provider == nil {
-		logrus.Fatal("failed to identify the CI/CD provider")
+// RunAction runs the github action.
+// providerName selects the CI/CD provider, if empty it is detected from the environment
+func RunAction(providerName string) error {
+	provider, err := cicd.GetProvider(providerName)
+	if err != nil {
+		return err
 	}
 
+	return RunActionWithProvider(provider)
+}
+
+// RunActionWithProvider runs the github action using the given CI/CD provider
+func RunActionWithProvider(provider cicd.Provider) error {
 	tag, err := provider.GetTag()
 	if err != nil {
 		return err
@@ -49,8 +64,6 @@ func RunAction() error {
 		return err
 	}
 
-	// this currently works only for GitHub.
-	// for travisci and circleci it always return false, nil
 	prerelease, err := provider.IsPreRelease(owner, repo, tag)
 	if err != nil {
 		return err
@@ -68,7 +81,13 @@ func RunAction() error {
 		PluginOwner:        owner,
 		PluginRepo:         repo,
 		PluginReleaseActor: actor,
-		TemplateFile:       templateFile,
+		TemplateFile:       relativeTemplateFile(provider.GetWorkDirectory(), templateFile),
+		Index:              os.Getenv("INPUT_KREW_INDEX"),
+	}
+
+	err = waitForAssets(templateFile, releaseRequest)
+	if err != nil {
+		return err
 	}
 
 	pluginName, pluginManifest, err := source.ProcessTemplate(templateFile, releaseRequest)
@@ -88,6 +107,84 @@ func RunAction() error {
 	return nil
 }
 
+// relativeTemplateFile returns the path of template file relative to the root of plugin
+// repo, which the bot uses to fetch the template from the plugin repo at the release tag
+func relativeTemplateFile(workdir, templateFile string) string {
+	abs, err := filepath.Abs(templateFile)
+	if err != nil {
+		return templateFile
+	}
+
+	root, err := filepath.Abs(repoRoot(workdir, abs))
+	if err != nil {
+		return templateFile
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return templateFile
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// repoRoot returns the root of the plugin repo checkout containing the template file, i.e.
+// the nearest directory with .git. it falls back to GITHUB_WORKSPACE, and then the workdir
+func repoRoot(workdir, templateFile string) string {
+	for dir := filepath.Dir(templateFile); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	if os.Getenv("GITHUB_WORKSPACE") != "" {
+		return os.Getenv("GITHUB_WORKSPACE")
+	}
+
+	return workdir
+}
+
+// waitForAssets waits for the assets of the release referenced by the template
+// to be uploaded, if input wait_for_assets_timeout is set. assets not part of
+// the github release of the plugin are not waited for
+func waitForAssets(templateFile string, request *source.ReleaseRequest) error {
+	v := os.Getenv("INPUT_WAIT_FOR_ASSETS_TIMEOUT")
+	if v == "" {
+		return nil
+	}
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid input wait_for_assets_timeout %q. error: %v", v, err)
+	}
+
+	uris, err := source.GetAssetURLs(templateFile, request)
+	if err != nil {
+		return err
+	}
+
+	assets := []string{}
+	for _, uri := range uris {
+		asset, ok := source.ParseGitHubReleaseAssetURL(uri)
+		if !ok || asset.Owner != request.PluginOwner || asset.Repo != request.PluginRepo || asset.Tag != request.TagName {
+			continue
+		}
+
+		assets = append(assets, asset.Name)
+	}
+
+	if len(assets) == 0 {
+		return nil
+	}
+
+	logrus.Infof("waiting up to %s for %d release assets to be uploaded", timeout, len(assets))
+	return github.WaitForReleaseAssets(request.PluginOwner, request.PluginRepo, request.TagName, assets, timeout)
+}
+
 func submitForPR(request *source.ReleaseRequest) (string, error) {
 	body, err := json.Marshal(request)
 	if err != nil {
@@ -101,8 +198,24 @@ func submitForPR(request *source.ReleaseRequest) (string, error) {
 
 	req.Header.Add("content-type", "application/json")
 
+	token, err := getOIDCToken()
+	if err != nil {
+		return "", err
+	}
+
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	secret := getWebhookSecret()
+	if token == "" && secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Add(TimestampHeader, timestamp)
+		req.Header.Add(SignatureHeader, sign(secret, timestamp, body))
+	}
+
 	client := http.Client{
-		Timeout: time.Duration(30 * time.Second),
+		Timeout: webhookTimeout(),
 	}
 
 	resp, err := client.Do(req)
@@ -120,13 +233,40 @@ func submitForPR(request *source.ReleaseRequest) (string, error) {
 		return "", fmt.Errorf("expected status code %d got %d. body: %s", http.StatusOK, resp.StatusCode, string(respBody))
 	}
 
+	// the bot responds with the status of release request, e.g. if the PR was
+	// already open or merged. older versions respond with plain text message
+	result := struct {
+		Message string `json:"message"`
+	}{}
+	if json.Unmarshal(respBody, &result) == nil && result.Message != "" {
+		return result.Message, nil
+	}
+
 	return string(respBody), nil
 }
 
+// webhookTimeout returns the timeout for the release request from INPUT_WEBHOOK_TIMEOUT
+// env, e.g. '5m'. The bot may download the release assets to verify the manifest,
+// so the default is longer than the download timeout of the bot
+func webhookTimeout() time.Duration {
+	v := os.Getenv("INPUT_WEBHOOK_TIMEOUT")
+	if v == "" {
+		return defaultWebhookTimeout
+	}
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout <= 0 {
+		logrus.Warnf("invalid webhook timeout %q, using default %s", v, defaultWebhookTimeout)
+		return defaultWebhookTimeout
+	}
+
+	return timeout
+}
+
 func getWebhookURL() string {
 	if os.Getenv("KREW_RELEASE_BOT_WEBHOOK_URL") != "" {
 		return os.Getenv("KREW_RELEASE_BOT_WEBHOOK_URL")
 	}
 
-	return "https://krew-release-bot.rajatjindal.com/github-action-webhook"
+	return defaultWebhookURL
 }
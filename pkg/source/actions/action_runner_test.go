@@ -2,7 +2,9 @@ package actions
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -82,15 +84,40 @@ func TestRunAction(t *testing.T) {
 				gock.New("https://github.com").
 					Times(4).
 					Get("/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz").
-					Reply(404).
-					BodyString("darwin-amd64-v0.0.2.tar.gz not found")
+					Reply(500).
+					BodyString("internal server error")
 
 				gock.New("https://github.com").
 					Get("/foo-bar/my-awesome-plugin/releases/download/v0.0.2/linux-amd64-v0.0.2.tar.gz").
 					Reply(200).
 					BodyString("linux-amd64")
 			},
-			expectedError: `template: .krew.yaml:13:6: executing ".krew.yaml" at <addURIAndSha "https://github.com/foo-bar/my-awesome-plugin/releases/download/{{ .TagName }}/darwin-amd64-{{ .TagName }}.tar.gz" .TagName>: error calling addURIAndSha: downloading file https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz failed. status code: 404, expected: 200`,
+			expectedError: `asset https://github.com/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz: downloading failed after 4 attempt(s). status code: 500, expected: 200`,
+		},
+		{
+			name: "release assets are not uploaded before timeout",
+			setup: func() {
+				os.Setenv("INPUT_WAIT_FOR_ASSETS_TIMEOUT", "1s")
+
+				gock.New("https://api.github.com").
+					Times(2).
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(releaseWithAssetsUploading)
+			},
+			expectedError: `timed out after 1s waiting for release assets: darwin-amd64-v0.0.2.tar.gz (state: new), linux-amd64-v0.0.2.tar.gz (not found)`,
+		},
+		{
+			name: "invalid wait for assets timeout",
+			setup: func() {
+				os.Setenv("INPUT_WAIT_FOR_ASSETS_TIMEOUT", "forever")
+
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(releaseWithAssets)
+			},
+			expectedError: `invalid input wait_for_assets_timeout "forever". error: time: invalid duration "forever"`,
 		},
 		{
 			name: "release have assets",
@@ -113,8 +140,43 @@ func TestRunAction(t *testing.T) {
 				gock.New("https://krew-release-bot.rajatjindal.com").
 					Post("/github-action-webhook").
 					Reply(200).
-					JSON("PR https://github.com/kubernetes-sigs/krew-index/pull/26 opened successfully")
+					JSON(map[string]string{
+						"status":  "created",
+						"pr":      "https://github.com/kubernetes-sigs/krew-index/pull/26",
+						"message": `PR "https://github.com/kubernetes-sigs/krew-index/pull/26" submitted successfully`,
+					})
+
+			},
+		},
+		{
+			name: "release to custom krew index",
+			setup: func() {
+				os.Setenv("INPUT_KREW_INDEX", "my-org/krew-index")
+
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/releases/tags/v0.0.2").
+					Reply(200).
+					BodyString(releaseWithAssets)
+
+				gock.New("https://github.com").
+					Get("/foo-bar/my-awesome-plugin/releases/download/v0.0.2/darwin-amd64-v0.0.2.tar.gz").
+					Reply(200).
+					BodyString("darwin-amd64-v0.0.2.tar.gz")
+
+				gock.New("https://github.com").
+					Get("/foo-bar/my-awesome-plugin/releases/download/v0.0.2/linux-amd64-v0.0.2.tar.gz").
+					Reply(200).
+					BodyString("linux-amd64")
 
+				gock.New("https://krew-release-bot.rajatjindal.com").
+					Post("/github-action-webhook").
+					BodyString(`"index":"my-org/krew-index"`).
+					Reply(200).
+					JSON(map[string]string{
+						"status":  "created",
+						"pr":      "https://github.com/my-org/krew-index/pull/26",
+						"message": `PR "https://github.com/my-org/krew-index/pull/26" submitted successfully`,
+					})
 			},
 		},
 	}
@@ -131,7 +193,7 @@ func TestRunAction(t *testing.T) {
 				tc.setup()
 			}
 
-			err := RunAction()
+			err := RunAction("")
 			assertError(t, tc.expectedError, err)
 			logrus.Error(gock.GetUnmatchedRequests())
 
@@ -144,6 +206,84 @@ func TestRunAction(t *testing.T) {
 	}
 }
 
+func TestRelativeTemplateFile(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "plugin")
+	assert.Nil(t, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(repo, "deploy", "krew"), 0755))
+
+	testcases := []struct {
+		name         string
+		workspace    string
+		workdir      string
+		templateFile string
+		expected     string
+	}{
+		{
+			name:         "workdir is the repo root",
+			workdir:      repo,
+			templateFile: filepath.Join(repo, ".krew.yaml"),
+			expected:     ".krew.yaml",
+		},
+		{
+			name:         "workdir is a directory in the repo",
+			workdir:      filepath.Join(repo, "deploy"),
+			templateFile: filepath.Join(repo, "deploy", "krew", "plugin.yaml"),
+			expected:     "deploy/krew/plugin.yaml",
+		},
+		{
+			name:         "not a git checkout, relative to workspace",
+			workspace:    dir,
+			workdir:      filepath.Join(dir, "other"),
+			templateFile: filepath.Join(dir, "other", ".krew.yaml"),
+			expected:     "other/.krew.yaml",
+		},
+		{
+			name:         "not a git checkout, relative to workdir",
+			workdir:      filepath.Join(dir, "other"),
+			templateFile: filepath.Join(dir, "other", ".krew.yaml"),
+			expected:     ".krew.yaml",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GITHUB_WORKSPACE", tc.workspace)
+			assert.Equal(t, tc.expected, relativeTemplateFile(tc.workdir, tc.templateFile))
+		})
+	}
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected time.Duration
+	}{
+		{
+			name:     "default",
+			expected: defaultWebhookTimeout,
+		},
+		{
+			name:     "from input",
+			input:    "20m",
+			expected: 20 * time.Minute,
+		},
+		{
+			name:     "invalid input",
+			input:    "forever",
+			expected: defaultWebhookTimeout,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("INPUT_WEBHOOK_TIMEOUT", tc.input)
+			assert.Equal(t, tc.expected, webhookTimeout())
+		})
+	}
+}
+
 const preRelease = `{
 	"id": 22569944,
 	"tag_name": "v0.0.2",
@@ -170,6 +310,21 @@ const releaseWithAssets = `{
 	]
 }`
 
+const releaseWithAssetsUploading = `{
+	"id": 22569944,
+	"tag_name": "v0.0.2",
+	"name": "v0.0.2",
+	"prerelease": false,
+	"assets": [
+		{
+			"id": 16605457,
+			"node_id": "MDEyOlJlbGVhc2VBc3NldDE2NjA1NDU3",
+			"name": "darwin-amd64-v0.0.2.tar.gz",
+			"state": "new"
+		}
+	]
+}`
+
 func setupEnvironment() {
 	os.Setenv("GITHUB_REPOSITORY", "foo-bar/my-awesome-plugin")
 	os.Setenv("GITHUB_ACTOR", "karthik-aryan")
@@ -0,0 +1,394 @@
+package actions
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+)
+
+const (
+	defaultOIDCIssuer = "https://token.actions.githubusercontent.com"
+
+	// jwksCacheTTL is the duration for which the fetched keys are cached
+	jwksCacheTTL = 10 * time.Minute
+
+	// jwksRefetchInterval is the minimum interval between fetching the keys
+	jwksRefetchInterval = time.Minute
+)
+
+func unauthorized(format string, args ...interface{}) error {
+	return &source.AuthError{StatusCode: http.StatusUnauthorized, Err: fmt.Errorf(format, args...)}
+}
+
+func forbidden(format string, args ...interface{}) error {
+	return &source.AuthError{StatusCode: http.StatusForbidden, Err: fmt.Errorf(format, args...)}
+}
+
+func getOIDCIssuer() string {
+	if os.Getenv("KREW_RELEASE_BOT_OIDC_ISSUER") != "" {
+		return os.Getenv("KREW_RELEASE_BOT_OIDC_ISSUER")
+	}
+
+	return defaultOIDCIssuer
+}
+
+// getOIDCAudience returns the audience of the token. It defaults to the webhook url, so
+// that the token issued for one bot cannot be replayed to another bot
+func getOIDCAudience() string {
+	if os.Getenv("KREW_RELEASE_BOT_OIDC_AUDIENCE") != "" {
+		return os.Getenv("KREW_RELEASE_BOT_OIDC_AUDIENCE")
+	}
+
+	return getWebhookURL()
+}
+
+func getOIDCJWKSURL() string {
+	if os.Getenv("KREW_RELEASE_BOT_OIDC_JWKS_URL") != "" {
+		return os.Getenv("KREW_RELEASE_BOT_OIDC_JWKS_URL")
+	}
+
+	return strings.TrimSuffix(getOIDCIssuer(), "/") + "/.well-known/jwks"
+}
+
+// oidcClaims are the claims of github actions oidc token we care about
+type oidcClaims struct {
+	Issuer     string   `json:"iss"`
+	Audience   audience `json:"aud"`
+	Expiry     int64    `json:"exp"`
+	NotBefore  int64    `json:"nbf"`
+	Repository string   `json:"repository"`
+	Ref        string   `json:"ref"`
+}
+
+// audience is the aud claim, which can be a string or a list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+
+	*a = multiple
+	return nil
+}
+
+func (a audience) contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verifyOIDCToken verifies the github actions oidc token, and that it was
+// issued for the workflow run of the plugin repo and tag being released
+func verifyOIDCToken(client *github.Client, token string, request *source.ReleaseRequest) error {
+	claims, err := parseAndVerifyJWT(token, getOIDCJWKSURL())
+	if err != nil {
+		return unauthorized("invalid oidc token. error: %v", err)
+	}
+
+	if claims.Issuer != getOIDCIssuer() {
+		return unauthorized("invalid oidc token. expected issuer %q, found %q", getOIDCIssuer(), claims.Issuer)
+	}
+
+	if !claims.Audience.contains(getOIDCAudience()) {
+		return unauthorized("invalid oidc token. expected audience %q, found %q", getOIDCAudience(), strings.Join(claims.Audience, ","))
+	}
+
+	now := time.Now().Unix()
+	if claims.Expiry == 0 || now > claims.Expiry {
+		return unauthorized("invalid oidc token. token is expired")
+	}
+
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return unauthorized("invalid oidc token. token is not valid yet")
+	}
+
+	repository := fmt.Sprintf("%s/%s", request.PluginOwner, request.PluginRepo)
+	if !strings.EqualFold(claims.Repository, repository) {
+		return forbidden("oidc token is for repository %q, but release request is for %q", claims.Repository, repository)
+	}
+
+	// the tag is resolved from the commit when the workflow runs for a branch, or set
+	// using input krew_plugin_release_tag. the tag must then exist in the plugin repo
+	if claims.Ref == fmt.Sprintf("refs/tags/%s", request.TagName) {
+		return nil
+	}
+
+	return verifyTag(client, claims.Ref, request)
+}
+
+// verifyTag verifies that the tag of release request exists in the plugin repo
+func verifyTag(client *github.Client, ref string, request *source.ReleaseRequest) error {
+	_, resp, err := client.Git.GetRef(
+		context.TODO(),
+		request.PluginOwner,
+		request.PluginRepo,
+		fmt.Sprintf("tags/%s", request.TagName),
+	)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return forbidden("oidc token is for ref %q, and tag %q of release request is not found in %s/%s", ref, request.TagName, request.PluginOwner, request.PluginRepo)
+	}
+
+	return err
+}
+
+// parseAndVerifyJWT verifies the RS256 signature of the jwt
+// using keys from jwksURL, and returns its claims
+func parseAndVerifyJWT(token, jwksURL string) (*oidcClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed jwt header. error: %v", err)
+	}
+
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", header.Alg)
+	}
+
+	key, err := getJWK(jwksURL, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt signature. error: %v", err)
+	}
+
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid jwt signature")
+	}
+
+	claims := &oidcClaims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, fmt.Errorf("malformed jwt claims. error: %v", err)
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// jwksCache caches the keys fetched from jwks url
+type jwksCache struct {
+	sync.Mutex
+	keys      map[string]map[string]*rsa.PublicKey
+	fetched   map[string]time.Time
+	attempted map[string]time.Time
+	inflight  map[string]chan struct{}
+}
+
+var keysCache = newJWKSCache()
+
+func newJWKSCache() *jwksCache {
+	return &jwksCache{
+		keys:      map[string]map[string]*rsa.PublicKey{},
+		fetched:   map[string]time.Time{},
+		attempted: map[string]time.Time{},
+		inflight:  map[string]chan struct{}{},
+	}
+}
+
+// getJWK returns the key with kid from jwks url. keys are refetched if kid is not
+// found, to handle rotation of the keys. The keys are fetched without holding the
+// lock, and at most once every jwksRefetchInterval, so that tokens with unknown
+// kid cannot make every request wait for the keys to be fetched
+func getJWK(jwksURL, kid string) (*rsa.PublicKey, error) {
+	keysCache.Lock()
+	if key, ok := keysCache.keys[jwksURL][kid]; ok && time.Since(keysCache.fetched[jwksURL]) < jwksCacheTTL {
+		keysCache.Unlock()
+		return key, nil
+	}
+
+	if done, ok := keysCache.inflight[jwksURL]; ok {
+		keysCache.Unlock()
+		<-done
+		return lookupJWK(jwksURL, kid)
+	}
+
+	if time.Since(keysCache.attempted[jwksURL]) < jwksRefetchInterval {
+		keysCache.Unlock()
+		return lookupJWK(jwksURL, kid)
+	}
+
+	done := make(chan struct{})
+	keysCache.inflight[jwksURL] = done
+	keysCache.attempted[jwksURL] = time.Now()
+	keysCache.Unlock()
+
+	keys, err := fetchJWKS(jwksURL)
+
+	keysCache.Lock()
+	if err == nil {
+		keysCache.keys[jwksURL] = keys
+		keysCache.fetched[jwksURL] = time.Now()
+	}
+	delete(keysCache.inflight, jwksURL)
+	close(done)
+	keysCache.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lookupJWK(jwksURL, kid)
+}
+
+// lookupJWK returns the key with kid from the cached keys of jwks url
+func lookupJWK(jwksURL, kid string) (*rsa.PublicKey, error) {
+	keysCache.Lock()
+	defer keysCache.Unlock()
+
+	key, ok := keysCache.keys[jwksURL][kid]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found in %s", kid, jwksURL)
+	}
+
+	return key, nil
+}
+
+func fetchJWKS(jwksURL string) (map[string]*rsa.PublicKey, error) {
+	client := http.Client{
+		Timeout: time.Duration(30 * time.Second),
+	}
+
+	resp, err := client.Get(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks from %s failed. status code: %d, expected: %d", jwksURL, resp.StatusCode, http.StatusOK)
+	}
+
+	jwks := struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q. error: %v", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q. error: %v", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// getOIDCToken requests the oidc token for the workflow run from github
+// actions. it returns empty token if not running in github actions, or if
+// the workflow does not have 'id-token: write' permission
+func getOIDCToken() (string, error) {
+	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	q := req.URL.Query()
+	q.Set("audience", getOIDCAudience())
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+
+	client := http.Client{
+		Timeout: time.Duration(30 * time.Second),
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting oidc token failed. expected status code %d got %d. body: %s", http.StatusOK, resp.StatusCode, string(body))
+	}
+
+	token := struct {
+		Value string `json:"value"`
+	}{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+
+	return token.Value, nil
+}
@@ -0,0 +1,152 @@
+package actions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SignatureHeader is the header with hmac signature of the request
+	SignatureHeader = "X-Krew-Release-Bot-Signature"
+
+	// TimestampHeader is the header with unix timestamp at which the request was signed
+	TimestampHeader = "X-Krew-Release-Bot-Timestamp"
+
+	signaturePrefix = "sha256="
+
+	// maxSignatureAge is the duration for which a signed request is accepted
+	maxSignatureAge = 5 * time.Minute
+)
+
+// WebhookSecrets is the config file with shared secrets used to sign the release requests
+type WebhookSecrets struct {
+	// Repos is the map of <owner>/<repo> to the secret for the repo
+	Repos map[string]string `json:"repos"`
+}
+
+// LoadWebhookSecrets loads the webhook secrets from the config file
+func LoadWebhookSecrets(file string) (*WebhookSecrets, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := &WebhookSecrets{}
+	err = yaml.Unmarshal(data, secrets)
+	if err != nil {
+		return nil, fmt.Errorf("parsing webhook secrets file %s. error: %v", file, err)
+	}
+
+	for repo, secret := range secrets.Repos {
+		if secret == "" {
+			return nil, fmt.Errorf("empty secret for repo %q in webhook secrets file %s", repo, file)
+		}
+	}
+
+	return secrets, nil
+}
+
+// secret returns the secret for the repo
+func (s *WebhookSecrets) secret(owner, repo string) string {
+	if s == nil {
+		return ""
+	}
+
+	name := fmt.Sprintf("%s/%s", owner, repo)
+	for k, v := range s.Repos {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// sign returns the signature for the body signed at timestamp
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// seenSignatures tracks the signatures already accepted, to reject replays of the same
+// request within maxSignatureAge. This is best-effort, as the signatures are only tracked in
+// memory of the process. A replay handled by another replica, or a new lambda instance, is
+// not detected, and is only limited by maxSignatureAge
+type seenSignatures struct {
+	sync.Mutex
+	seen map[string]time.Time
+}
+
+var acceptedSignatures = &seenSignatures{
+	seen: map[string]time.Time{},
+}
+
+// add records the signature, returning false if it was already seen
+func (s *seenSignatures) add(signature string, now time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	for k, t := range s.seen {
+		if now.Sub(t) > maxSignatureAge {
+			delete(s.seen, k)
+		}
+	}
+
+	if _, ok := s.seen[signature]; ok {
+		return false
+	}
+
+	s.seen[signature] = now
+	return true
+}
+
+// verifySignature verifies the hmac signature of the body using the secret for the plugin repo
+func verifySignature(secrets *WebhookSecrets, signature, timestamp string, body []byte, request *source.ReleaseRequest) error {
+	secret := secrets.secret(request.PluginOwner, request.PluginRepo)
+	if secret == "" {
+		return unauthorized("no webhook secret configured for repo %s/%s", request.PluginOwner, request.PluginRepo)
+	}
+
+	if timestamp == "" {
+		return unauthorized("missing %s header", TimestampHeader)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return unauthorized("invalid %s header %q", TimestampHeader, timestamp)
+	}
+
+	now := time.Now()
+	age := now.Sub(time.Unix(unix, 0))
+	if age > maxSignatureAge || age < -maxSignatureAge {
+		return unauthorized("request signed at %s is outside the allowed window of %s", time.Unix(unix, 0).UTC().Format(time.RFC3339), maxSignatureAge)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(sign(secret, timestamp, body))) {
+		return unauthorized("invalid signature")
+	}
+
+	if !acceptedSignatures.add(signature, now) {
+		return unauthorized("request with this signature was already received")
+	}
+
+	return nil
+}
+
+// getWebhookSecret returns the secret to sign the release request with
+func getWebhookSecret() string {
+	return os.Getenv("INPUT_WEBHOOK_SECRET")
+}
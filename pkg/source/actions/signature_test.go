@@ -0,0 +1,114 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWebhookSecrets(t *testing.T) {
+	secrets, err := LoadWebhookSecrets("data/webhook-secrets.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "my-secret", secrets.secret("foo-bar", "my-awesome-plugin"))
+	assert.Equal(t, "my-secret", secrets.secret("Foo-Bar", "My-Awesome-Plugin"))
+	assert.Equal(t, "", secrets.secret("foo-bar", "unknown-plugin"))
+
+	_, err = LoadWebhookSecrets("data/does-not-exist.yaml")
+	assertError(t, "open data/does-not-exist.yaml: no such file or directory", err)
+}
+
+func TestParseSigned(t *testing.T) {
+	secrets, err := LoadWebhookSecrets("data/webhook-secrets.yaml")
+	assert.Nil(t, err)
+
+	body, err := json.Marshal(&source.ReleaseRequest{
+		TagName:     "v0.0.2",
+		PluginName:  "my-awesome-plugin",
+		PluginOwner: "foo-bar",
+		PluginRepo:  "my-awesome-plugin",
+	})
+	assert.Nil(t, err)
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	testcases := []struct {
+		name          string
+		secrets       *WebhookSecrets
+		timestamp     string
+		signature     string
+		expectedError string
+	}{
+		{
+			name:      "valid signature",
+			secrets:   secrets,
+			timestamp: now,
+			signature: sign("my-secret", now, body),
+		},
+		{
+			name:          "replayed request",
+			secrets:       secrets,
+			timestamp:     now,
+			signature:     sign("my-secret", now, body),
+			expectedError: "request with this signature was already received",
+		},
+		{
+			name:          "signed with secret of other repo",
+			secrets:       secrets,
+			timestamp:     now,
+			signature:     sign("other-secret", now, body),
+			expectedError: "invalid signature",
+		},
+		{
+			name:          "timestamp does not match signature",
+			secrets:       secrets,
+			timestamp:     strconv.FormatInt(time.Now().Unix()-1, 10),
+			signature:     sign("my-secret", now, body),
+			expectedError: "invalid signature",
+		},
+		{
+			name:          "stale timestamp",
+			secrets:       secrets,
+			timestamp:     stale,
+			signature:     sign("my-secret", stale, body),
+			expectedError: "request signed at " + time.Unix(time.Now().Add(-10*time.Minute).Unix(), 0).UTC().Format(time.RFC3339) + " is outside the allowed window of 5m0s",
+		},
+		{
+			name:          "missing timestamp",
+			secrets:       secrets,
+			signature:     sign("my-secret", now, body),
+			expectedError: "missing X-Krew-Release-Bot-Timestamp header",
+		},
+		{
+			name:          "no secrets configured",
+			timestamp:     now,
+			signature:     sign("my-secret", now, body),
+			expectedError: "no webhook secret configured for repo foo-bar/my-awesome-plugin",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &GithubActions{Secrets: tc.secrets}
+
+			r, err := http.NewRequest(http.MethodPost, "/github-action-webhook", bytes.NewReader(body))
+			assert.Nil(t, err)
+			r.Header.Set(SignatureHeader, tc.signature)
+			if tc.timestamp != "" {
+				r.Header.Set(TimestampHeader, tc.timestamp)
+			}
+
+			_, err = hook.Parse(r)
+			assertError(t, tc.expectedError, err)
+			if tc.expectedError != "" {
+				assertStatusCode(t, http.StatusUnauthorized, err)
+			}
+		})
+	}
+}
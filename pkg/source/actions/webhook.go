@@ -4,17 +4,46 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-github/v66/github"
 	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/sirupsen/logrus"
 )
 
 // GithubActions is github webhook handler
-type GithubActions struct{}
+type GithubActions struct {
+	// RequireVerified rejects requests without oidc token or signature. requests
+	// with a token or signature are always verified
+	RequireVerified bool
+
+	// Secrets are the per repo secrets used to verify signed requests
+	Secrets *WebhookSecrets
+
+	// GithubClient is used to verify that the tag of release request exists, when
+	// the oidc token is not for the tag. requests are unauthenticated if not set
+	GithubClient *github.Client
+}
 
 // NewGithubActions gets new git webhook instance
 func NewGithubActions() (*GithubActions, error) {
-	return &GithubActions{}, nil
+	hook := &GithubActions{
+		RequireVerified: os.Getenv("KREW_RELEASE_BOT_REQUIRE_VERIFICATION") == "true",
+	}
+
+	file := os.Getenv("KREW_RELEASE_BOT_WEBHOOK_SECRETS_FILE")
+	if file != "" {
+		secrets, err := LoadWebhookSecrets(file)
+		if err != nil {
+			return nil, err
+		}
+
+		hook.Secrets = secrets
+	}
+
+	return hook, nil
 }
 
 // Parse validates the request
@@ -31,6 +60,11 @@ func (w *GithubActions) Parse(r *http.Request) (*source.ReleaseRequest, error) {
 		return nil, err
 	}
 
+	err = w.verify(r.Header.Get, body, request)
+	if err != nil {
+		return nil, err
+	}
+
 	return request, nil
 }
 
@@ -42,5 +76,63 @@ func (w *GithubActions) ParseLambdaRequest(r events.APIGatewayProxyRequest) (*so
 		return nil, err
 	}
 
+	err = w.verify(func(name string) string {
+		return getLambdaHeader(r, name)
+	}, []byte(r.Body), request)
+	if err != nil {
+		return nil, err
+	}
+
 	return request, nil
 }
+
+// verify verifies the oidc token from the authorization header,
+// or the signature of the body if the request is signed
+func (w *GithubActions) verify(header func(string) string, body []byte, request *source.ReleaseRequest) error {
+	authorization := header("Authorization")
+	if authorization == "" {
+		signature := header(SignatureHeader)
+		if signature != "" {
+			return verifySignature(w.Secrets, signature, header(TimestampHeader), body, request)
+		}
+
+		if !w.RequireVerified {
+			logrus.Warnf("accepting unverified release request for %s/%s. set KREW_RELEASE_BOT_REQUIRE_VERIFICATION=true to reject such requests", request.PluginOwner, request.PluginRepo)
+			return nil
+		}
+
+		return unauthorized("missing oidc token or signature. add 'id-token: write' to the permissions of the workflow, or set input 'webhook_secret' when not using github actions")
+	}
+
+	token := strings.TrimPrefix(authorization, "Bearer ")
+	if token == authorization {
+		return unauthorized("invalid authorization header. expected bearer token")
+	}
+
+	return verifyOIDCToken(w.getGithubClient(), token, request)
+}
+
+func (w *GithubActions) getGithubClient() *github.Client {
+	if w.GithubClient != nil {
+		return w.GithubClient
+	}
+
+	return github.NewClient(nil)
+}
+
+// getLambdaHeader returns the header from lambda request, ignoring the case of the header name
+func getLambdaHeader(r events.APIGatewayProxyRequest, name string) string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	for k, v := range r.MultiValueHeaders {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+
+	return ""
+}
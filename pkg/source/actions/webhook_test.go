@@ -0,0 +1,328 @@
+package actions
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testJWKSURL = "https://token.actions.example.com/.well-known/jwks"
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	assert.Nil(t, err)
+
+	payload, err := json.Marshal(claims)
+	assert.Nil(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hashed := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	assert.Nil(t, err)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func mockJWKS(key *rsa.PrivateKey, kid string) {
+	gock.New(testJWKSURL).
+		Get("").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kid": kid,
+					"kty": "RSA",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+}
+
+func TestParse(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":        defaultOIDCIssuer,
+			"aud":        defaultWebhookURL,
+			"exp":        time.Now().Add(5 * time.Minute).Unix(),
+			"nbf":        time.Now().Add(-1 * time.Minute).Unix(),
+			"repository": "foo-bar/my-awesome-plugin",
+			"ref":        "refs/tags/v0.0.2",
+		}
+	}
+
+	testcases := []struct {
+		name               string
+		authorization      func() string
+		requireVerified    bool
+		setupMocks         func()
+		expectedError      string
+		expectedStatusCode int
+	}{
+		{
+			name: "valid token",
+			authorization: func() string {
+				return "Bearer " + signJWT(t, key, "key-1", validClaims())
+			},
+		},
+		{
+			name: "audience is a list",
+			authorization: func() string {
+				claims := validClaims()
+				claims["aud"] = []string{"something-else", defaultWebhookURL}
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+		},
+		{
+			name:               "missing token",
+			authorization:      func() string { return "" },
+			requireVerified:    true,
+			expectedError:      "missing oidc token or signature. add 'id-token: write' to the permissions of the workflow, or set input 'webhook_secret' when not using github actions",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "missing token allowed when verification is not required",
+			authorization: func() string { return "" },
+		},
+		{
+			name:               "not a bearer token",
+			authorization:      func() string { return "Basic Zm9vOmJhcg==" },
+			expectedError:      "invalid authorization header. expected bearer token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "malformed token",
+			authorization:      func() string { return "Bearer not-a-jwt" },
+			expectedError:      "invalid oidc token. error: malformed jwt",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "token signed by other key",
+			authorization: func() string {
+				return "Bearer " + signJWT(t, otherKey, "key-1", validClaims())
+			},
+			expectedError:      "invalid oidc token. error: invalid jwt signature",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown key",
+			authorization: func() string {
+				return "Bearer " + signJWT(t, key, "key-2", validClaims())
+			},
+			expectedError:      `invalid oidc token. error: key "key-2" not found in ` + testJWKSURL,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong issuer",
+			authorization: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://evil.example.com"
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+			expectedError:      `invalid oidc token. expected issuer "https://token.actions.githubusercontent.com", found "https://evil.example.com"`,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong audience",
+			authorization: func() string {
+				claims := validClaims()
+				claims["aud"] = "something-else"
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+			expectedError:      `invalid oidc token. expected audience "https://krew-release-bot.rajatjindal.com/github-action-webhook", found "something-else"`,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			authorization: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-1 * time.Minute).Unix()
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+			expectedError:      "invalid oidc token. token is expired",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "token for other repository",
+			authorization: func() string {
+				claims := validClaims()
+				claims["repository"] = "evil/my-awesome-plugin"
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+			expectedError:      `oidc token is for repository "evil/my-awesome-plugin", but release request is for "foo-bar/my-awesome-plugin"`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "token for other ref",
+			authorization: func() string {
+				claims := validClaims()
+				claims["ref"] = "refs/heads/main"
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/git/ref/tags/v0.0.2").
+					MatchHeader("Authorization", "Bearer bot-token").
+					Times(2).
+					Reply(404).
+					JSON(map[string]string{"message": "Not Found"})
+			},
+			expectedError:      `oidc token is for ref "refs/heads/main", and tag "v0.0.2" of release request is not found in foo-bar/my-awesome-plugin`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "token for branch with tag in the repo",
+			authorization: func() string {
+				claims := validClaims()
+				claims["ref"] = "refs/heads/main"
+				return "Bearer " + signJWT(t, key, "key-1", claims)
+			},
+			setupMocks: func() {
+				gock.New("https://api.github.com").
+					Get("/repos/foo-bar/my-awesome-plugin/git/ref/tags/v0.0.2").
+					MatchHeader("Authorization", "Bearer bot-token").
+					Times(2).
+					Reply(200).
+					JSON(map[string]interface{}{
+						"ref":    "refs/tags/v0.0.2",
+						"object": map[string]string{"type": "commit", "sha": "aa218f56b14c9653891f9e74264a383fa43fefbd"},
+					})
+			},
+		},
+	}
+
+	os.Setenv("KREW_RELEASE_BOT_OIDC_JWKS_URL", testJWKSURL)
+	defer os.Unsetenv("KREW_RELEASE_BOT_OIDC_JWKS_URL")
+
+	body, err := json.Marshal(&source.ReleaseRequest{
+		TagName:     "v0.0.2",
+		PluginName:  "my-awesome-plugin",
+		PluginOwner: "foo-bar",
+		PluginRepo:  "my-awesome-plugin",
+	})
+	assert.Nil(t, err)
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			keysCache = newJWKSCache()
+			mockJWKS(key, "key-1")
+			if tc.setupMocks != nil {
+				tc.setupMocks()
+			}
+
+			hook := &GithubActions{
+				RequireVerified: tc.requireVerified,
+				GithubClient:    github.NewClient(nil).WithAuthToken("bot-token"),
+			}
+			authorization := tc.authorization()
+
+			r, err := http.NewRequest(http.MethodPost, "/github-action-webhook", bytes.NewReader(body))
+			assert.Nil(t, err)
+			if authorization != "" {
+				r.Header.Set("Authorization", authorization)
+			}
+
+			request, err := hook.Parse(r)
+			assertError(t, tc.expectedError, err)
+			assertStatusCode(t, tc.expectedStatusCode, err)
+			if tc.expectedError == "" {
+				assert.Equal(t, "v0.0.2", request.TagName)
+			}
+
+			keysCache = newJWKSCache()
+			mockJWKS(key, "key-1")
+
+			_, err = hook.ParseLambdaRequest(events.APIGatewayProxyRequest{
+				Headers: map[string]string{"authorization": authorization},
+				Body:    string(body),
+			})
+			assertError(t, tc.expectedError, err)
+			assertStatusCode(t, tc.expectedStatusCode, err)
+		})
+	}
+}
+
+func TestGetJWK(t *testing.T) {
+	defer gock.Off()
+	keysCache = newJWKSCache()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	mockJWKS(key, "key-1")
+	_, err = getJWK(testJWKSURL, "key-2")
+	assertError(t, `key "key-2" not found in `+testJWKSURL, err)
+	assert.True(t, gock.IsDone())
+
+	// keys are not refetched for unknown kid within the refetch interval
+	_, err = getJWK(testJWKSURL, "key-2")
+	assertError(t, `key "key-2" not found in `+testJWKSURL, err)
+
+	found, err := getJWK(testJWKSURL, "key-1")
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, found)
+
+	// keys are refetched for unknown kid after the refetch interval, e.g. when rotated
+	keysCache.attempted[testJWKSURL] = time.Now().Add(-jwksRefetchInterval)
+	mockJWKS(key, "key-2")
+	found, err = getJWK(testJWKSURL, "key-2")
+	assert.Nil(t, err)
+	assert.Equal(t, &key.PublicKey, found)
+	assert.True(t, gock.IsDone())
+}
+
+func assertStatusCode(t *testing.T, expectedStatusCode int, err error) {
+	if expectedStatusCode == 0 {
+		return
+	}
+
+	authErr, ok := err.(*source.AuthError)
+	assert.True(t, ok)
+	if ok {
+		assert.Equal(t, expectedStatusCode, authErr.StatusCode)
+	}
+}
+
+func TestGetOIDCToken(t *testing.T) {
+	defer gock.Off()
+	os.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "https://pipelines.actions.example.com/idtoken?api-version=2.0")
+	os.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	defer os.Unsetenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	defer os.Unsetenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+
+	gock.New("https://pipelines.actions.example.com").
+		Get("/idtoken").
+		MatchParam("api-version", "2.0").
+		MatchParam("audience", "https://krew-release-bot.rajatjindal.com/github-action-webhook").
+		MatchHeader("Authorization", "Bearer request-token").
+		Reply(200).
+		JSON(map[string]string{"value": "id-token"})
+
+	token, err := getOIDCToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "id-token", token)
+	assert.True(t, gock.IsDone())
+}
@@ -0,0 +1,300 @@
+package source
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// maxCachedReleases is the max number of github releases cached for resolving the assets
+const maxCachedReleases = 100
+
+// githubReleaseAssetRegex matches the browser download url of github release assets
+// e.g. https://github.com/<owner>/<repo>/releases/download/<tag>/<asset>
+var githubReleaseAssetRegex = regexp.MustCompile(`^/([^/]+)/([^/]+)/releases/download/([^/]+)/([^/]+)$`)
+
+// GitHubReleaseAsset is the asset of a github release
+type GitHubReleaseAsset struct {
+	Owner string
+	Repo  string
+	Tag   string
+	Name  string
+}
+
+// ParseGitHubReleaseAssetURL parses the browser download url of github release asset.
+// it returns false if uri is not a github release asset url
+func ParseGitHubReleaseAssetURL(uri string) (*GitHubReleaseAsset, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || !isGitHubHost(u.Host) {
+		return nil, false
+	}
+
+	m := githubReleaseAssetRegex.FindStringSubmatch(u.Path)
+	if m == nil {
+		return nil, false
+	}
+
+	return &GitHubReleaseAsset{Owner: m[1], Repo: m[2], Tag: m[3], Name: m[4]}, true
+}
+
+// isGitHubHost returns true if host is github.com or the
+// github enterprise server the action is running on (GITHUB_SERVER_URL)
+func isGitHubHost(host string) bool {
+	if host == "github.com" {
+		return true
+	}
+
+	serverURL, err := url.Parse(os.Getenv("GITHUB_SERVER_URL"))
+	return err == nil && serverURL.Host != "" && serverURL.Host == host
+}
+
+// newAssetRequest returns the request for downloading the asset.
+//
+// if GITHUB_TOKEN is set and uri is a github release asset, the asset is
+// downloaded using the releases api, which works for private repos as well.
+// otherwise the request is authenticated using INPUT_DOWNLOAD_TOKEN as bearer
+// token if the asset is on INPUT_DOWNLOAD_TOKEN_HOST, or using credentials
+// from netrc file if available
+func newAssetRequest(ctx context.Context, uri string) (*http.Request, error) {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		apiURL, err := resolveGitHubReleaseAsset(ctx, uri, token)
+		if err != nil {
+			logrus.Debugf("failed to resolve %s using github releases api. error: %v", uri, err)
+		}
+
+		if apiURL != "" {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("Accept", "application/octet-stream")
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token := os.Getenv("INPUT_DOWNLOAD_TOKEN"); token != "" && isDownloadTokenHost(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req, nil
+	}
+
+	if login, password, ok := netrcCredentials(req.URL.Hostname()); ok {
+		req.SetBasicAuth(login, password)
+	}
+
+	return req, nil
+}
+
+// isDownloadTokenHost returns true if u is on the host for which download token
+// is configured, using INPUT_DOWNLOAD_TOKEN_HOST env. e.g. 'artifacts.example.com'
+func isDownloadTokenHost(u *url.URL) bool {
+	host := os.Getenv("INPUT_DOWNLOAD_TOKEN_HOST")
+	if host == "" {
+		logrus.Warnf("not using download token for %s. set input download_token_host to the host of the assets", u.Host)
+		return false
+	}
+
+	return strings.EqualFold(u.Host, host) || strings.EqualFold(u.Hostname(), host)
+}
+
+// releases caches the github releases used to resolve the api url of assets, as the
+// release is the same for all the assets of a plugin, and for retries of the download
+var releases = newReleasesCache()
+
+type releasesCache struct {
+	sync.Mutex
+	releases map[string]*github.RepositoryRelease
+}
+
+func newReleasesCache() *releasesCache {
+	return &releasesCache{releases: map[string]*github.RepositoryRelease{}}
+}
+
+func (c *releasesCache) get(key string) *github.RepositoryRelease {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.releases[key]
+}
+
+func (c *releasesCache) set(key string, release *github.RepositoryRelease) {
+	c.Lock()
+	defer c.Unlock()
+
+	// the cache is only needed for the releases being rendered, so it is
+	// reset instead of growing without bounds for long running bot
+	if len(c.releases) >= maxCachedReleases {
+		c.releases = map[string]*github.RepositoryRelease{}
+	}
+
+	c.releases[key] = release
+}
+
+// findAssetURL returns the api url of asset with name in the release, or empty string if not found
+func findAssetURL(release *github.RepositoryRelease, name string) string {
+	for _, asset := range release.Assets {
+		if asset.GetName() == name {
+			return asset.GetURL()
+		}
+	}
+
+	return ""
+}
+
+// resolveGitHubReleaseAsset returns the api url of the release asset, or empty string
+// if uri is not a github release asset. The release is fetched again if the asset is
+// not found in the cached release, as it may have been uploaded since then
+func resolveGitHubReleaseAsset(ctx context.Context, uri, token string) (string, error) {
+	releaseAsset, ok := ParseGitHubReleaseAssetURL(uri)
+	if !ok {
+		return "", nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	client, ok, err := githubClientForHost(u.Host, token)
+	if err != nil || !ok {
+		return "", err
+	}
+
+	key := strings.Join([]string{u.Host, releaseAsset.Owner, releaseAsset.Repo, releaseAsset.Tag}, "/")
+	release := releases.get(key)
+	if release == nil || findAssetURL(release, releaseAsset.Name) == "" {
+		release, _, err = client.Repositories.GetReleaseByTag(ctx, releaseAsset.Owner, releaseAsset.Repo, releaseAsset.Tag)
+		if err != nil {
+			return "", err
+		}
+
+		releases.set(key, release)
+	}
+
+	if apiURL := findAssetURL(release, releaseAsset.Name); apiURL != "" {
+		return apiURL, nil
+	}
+
+	return "", fmt.Errorf("asset %s not found in release %s of %s/%s", releaseAsset.Name, releaseAsset.Tag, releaseAsset.Owner, releaseAsset.Repo)
+}
+
+// githubClientForHost returns the github client for host, if host is github.com or
+// the github enterprise server the action is running on (GITHUB_SERVER_URL)
+func githubClientForHost(host, token string) (*github.Client, bool, error) {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := github.NewClient(oauth2.NewClient(context.TODO(), ts))
+	if host == "github.com" {
+		return client, true, nil
+	}
+
+	serverURL, err := url.Parse(os.Getenv("GITHUB_SERVER_URL"))
+	if err != nil || serverURL.Host != host || os.Getenv("GITHUB_API_URL") == "" {
+		return nil, false, nil
+	}
+
+	apiURL := strings.TrimSuffix(os.Getenv("GITHUB_API_URL"), "/") + "/"
+	client, err = client.WithEnterpriseURLs(apiURL, apiURL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return client, true, nil
+}
+
+// netrcCredentials returns login and password for host from
+// netrc file at NETRC env, or ~/.netrc by default
+func netrcCredentials(host string) (string, string, bool) {
+	file := os.Getenv("NETRC")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+
+		file = filepath.Join(home, ".netrc")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", "", false
+	}
+	defer f.Close()
+
+	tokens := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+
+	// machine entries take precedence over default
+	machine, login, password := "", "", ""
+	defaultLogin, defaultPassword := "", ""
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if machine == host && login != "" {
+				return login, password, true
+			}
+
+			machine, login, password = "", "", ""
+			if i+1 < len(tokens) {
+				machine = tokens[i+1]
+				i++
+			}
+		case "default":
+			if machine == host && login != "" {
+				return login, password, true
+			}
+
+			machine, login, password = "default", "", ""
+		case "login", "password":
+			if i+1 >= len(tokens) {
+				break
+			}
+
+			value := tokens[i+1]
+			i++
+			if tokens[i-1] == "login" {
+				login = value
+			} else {
+				password = value
+			}
+
+			if machine == "default" {
+				defaultLogin, defaultPassword = login, password
+			}
+		}
+	}
+
+	if machine == host && login != "" {
+		return login, password, true
+	}
+
+	if defaultLogin != "" {
+		return defaultLogin, defaultPassword, true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,190 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestNetrcCredentials(t *testing.T) {
+	testcases := []struct {
+		name             string
+		netrc            string
+		host             string
+		expectedLogin    string
+		expectedPassword string
+		expectedFound    bool
+	}{
+		{
+			name:             "machine found",
+			netrc:            "machine example.com login foo password bar\nmachine other.com login baz password qux",
+			host:             "other.com",
+			expectedLogin:    "baz",
+			expectedPassword: "qux",
+			expectedFound:    true,
+		},
+		{
+			name: "multiline machine entry",
+			netrc: `machine example.com
+  login foo
+  password bar`,
+			host:             "example.com",
+			expectedLogin:    "foo",
+			expectedPassword: "bar",
+			expectedFound:    true,
+		},
+		{
+			name:             "default entry",
+			netrc:            "machine example.com login foo password bar\ndefault login anon password secret",
+			host:             "other.com",
+			expectedLogin:    "anon",
+			expectedPassword: "secret",
+			expectedFound:    true,
+		},
+		{
+			name:  "machine not found",
+			netrc: "machine example.com login foo password bar",
+			host:  "other.com",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			file := filepath.Join(t.TempDir(), ".netrc")
+			err := os.WriteFile(file, []byte(tc.netrc), 0600)
+			assert.Nil(t, err)
+			os.Setenv("NETRC", file)
+
+			login, password, found := netrcCredentials(tc.host)
+			assert.Equal(t, tc.expectedLogin, login)
+			assert.Equal(t, tc.expectedPassword, password)
+			assert.Equal(t, tc.expectedFound, found)
+		})
+	}
+}
+
+func TestAuthenticatedDownload(t *testing.T) {
+	testcases := []struct {
+		name         string
+		setup        func()
+		expectedAuth string
+	}{
+		{
+			name: "download token is used as bearer token",
+			setup: func() {
+				os.Setenv("INPUT_DOWNLOAD_TOKEN", "download-token")
+				os.Setenv("INPUT_DOWNLOAD_TOKEN_HOST", "127.0.0.1")
+			},
+			expectedAuth: "Bearer download-token",
+		},
+		{
+			name: "download token is not sent to other hosts",
+			setup: func() {
+				os.Setenv("INPUT_DOWNLOAD_TOKEN", "download-token")
+				os.Setenv("INPUT_DOWNLOAD_TOKEN_HOST", "artifacts.example.com")
+			},
+			expectedAuth: "",
+		},
+		{
+			name: "download token is not sent without its host",
+			setup: func() {
+				os.Setenv("INPUT_DOWNLOAD_TOKEN", "download-token")
+			},
+			expectedAuth: "",
+		},
+		{
+			name: "netrc credentials are used",
+			setup: func() {
+				file := filepath.Join(t.TempDir(), ".netrc")
+				_ = os.WriteFile(file, []byte("machine 127.0.0.1 login foo password bar"), 0600)
+				os.Setenv("NETRC", file)
+			},
+			expectedAuth: "Basic Zm9vOmJhcg==",
+		},
+		{
+			name:         "no credentials",
+			expectedAuth: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			os.Setenv("NETRC", filepath.Join(t.TempDir(), "non-existent"))
+
+			auth := ""
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				_, _ = w.Write([]byte("my-plugin-binary"))
+			}))
+			defer srv.Close()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			sha256, err := getSha256ForAsset(context.Background(), srv.URL+"/kubectl-whoami.tar.gz")
+			assert.Nil(t, err)
+			assert.Equal(t, "d9336538c1469e9ced64c5ee3f9c1bf7b7ef80ccc656c73bc244de35dfbf69d4", sha256)
+			assert.Equal(t, tc.expectedAuth, auth)
+		})
+	}
+}
+
+func TestPrivateGitHubReleaseAsset(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("GITHUB_TOKEN", "gh-token")
+	gock.DisableNetworking()
+	defer gock.Off()
+	releases = newReleasesCache()
+
+	// the release is fetched once for all of its assets
+
+	gock.New("https://api.github.com").
+		Get("/repos/foo-bar/my-private-plugin/releases/tags/v0.0.2").
+		MatchHeader("Authorization", "Bearer gh-token").
+		Reply(200).
+		BodyString(`{
+	"tag_name": "v0.0.2",
+	"assets": [
+		{
+			"id": 16605457,
+			"url": "https://api.github.com/repos/foo-bar/my-private-plugin/releases/assets/16605457",
+			"name": "darwin-amd64-v0.0.2.tar.gz"
+		},
+		{
+			"id": 16605458,
+			"url": "https://api.github.com/repos/foo-bar/my-private-plugin/releases/assets/16605458",
+			"name": "linux-amd64-v0.0.2.tar.gz"
+		}
+	]
+}`)
+
+	gock.New("https://api.github.com").
+		Get("/repos/foo-bar/my-private-plugin/releases/assets/16605457").
+		MatchHeader("Authorization", "Bearer gh-token").
+		MatchHeader("Accept", "application/octet-stream").
+		Reply(200).
+		BodyString("my-plugin-binary")
+
+	gock.New("https://api.github.com").
+		Get("/repos/foo-bar/my-private-plugin/releases/assets/16605458").
+		MatchHeader("Authorization", "Bearer gh-token").
+		MatchHeader("Accept", "application/octet-stream").
+		Reply(200).
+		BodyString("my-plugin-binary")
+
+	for _, asset := range []string{"darwin-amd64-v0.0.2.tar.gz", "linux-amd64-v0.0.2.tar.gz"} {
+		sha256, err := getSha256ForAsset(context.Background(), "https://github.com/foo-bar/my-private-plugin/releases/download/v0.0.2/"+asset)
+		assert.Nil(t, err)
+		assert.Equal(t, "d9336538c1469e9ced64c5ee3f9c1bf7b7ef80ccc656c73bc244de35dfbf69d4", sha256)
+	}
+	assert.True(t, gock.IsDone())
+}
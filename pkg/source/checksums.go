@@ -0,0 +1,246 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// checksumsAuto is the checksums url value that enables
+// discovering the checksums file next to the asset
+const checksumsAuto = "auto"
+
+// autoChecksumsFiles are the checksums files looked up in the release of
+// the asset, in order, when checksums url is 'auto'. {{ .Asset }} is the
+// name of the asset
+var autoChecksumsFiles = []string{
+	"{{ .Asset }}.sha256",
+	"checksums.txt",
+	"SHA256SUMS",
+	"sha256sums.txt",
+}
+
+var (
+	sha256Regex      = regexp.MustCompile(`^[a-fA-F0-9]{64}$`)
+	bsdChecksumRegex = regexp.MustCompile(`^SHA256 \((.+)\) = ([a-fA-F0-9]{64})$`)
+)
+
+// parseChecksums parses checksums file in sha256sum (and goreleaser) format
+// i.e. '<sha256>  <filename>' or '<sha256> *<filename>', or bsd format
+// i.e. 'SHA256 (<filename>) = <sha256>'. It returns a map of file name to sha256.
+// files containing only the sha256 are mapped with an empty file name
+func parseChecksums(data []byte) (map[string]string, error) {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := bsdChecksumRegex.FindStringSubmatch(line); m != nil {
+			checksums[path.Base(m[1])] = strings.ToLower(m[2])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !sha256Regex.MatchString(fields[0]) {
+			// the line is not quoted, as the file is remote content that ends up in responses
+			return nil, fmt.Errorf("invalid checksums file, line %d is not in sha256sum or bsd format", lineNum)
+		}
+
+		name := ""
+		if len(fields) > 1 {
+			name = path.Base(strings.TrimPrefix(strings.Join(fields[1:], " "), "*"))
+		}
+
+		checksums[name] = strings.ToLower(fields[0])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return checksums, nil
+}
+
+// lookupChecksum returns the sha256 for the asset from checksums
+func lookupChecksum(checksums map[string]string, asset string) (string, bool) {
+	if sha256, ok := checksums[asset]; ok {
+		return sha256, true
+	}
+
+	// file with only the sha256 in it, e.g. <asset>.sha256
+	if sha256, ok := checksums[""]; ok && len(checksums) == 1 {
+		return sha256, true
+	}
+
+	return "", false
+}
+
+// siblingURL returns the url of file name in the same directory as uri
+func siblingURL(uri, name string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	u.Path = path.Join(path.Dir(u.Path), name)
+	u.RawPath = ""
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+// checksumsResolver resolves sha256 for assets from checksums files.
+// it caches the checksums files, as usually all assets of a
+// release are listed in the same checksums file
+type checksumsResolver struct {
+	files map[string]map[string]string
+}
+
+func newChecksumsResolver() *checksumsResolver {
+	return &checksumsResolver{files: map[string]map[string]string{}}
+}
+
+// resolve returns the sha256 of asset uri from checksumsURL.
+// if checksumsURL is 'auto' and the asset is not listed
+// in any of the known checksums files, it returns false
+func (c *checksumsResolver) resolve(ctx context.Context, uri, checksumsURL string) (string, bool, error) {
+	asset := path.Base(uri)
+	if checksumsURL != checksumsAuto {
+		checksums, err := c.get(ctx, checksumsURL, true)
+		if err != nil {
+			return "", false, err
+		}
+
+		sha256, ok := lookupChecksum(checksums, asset)
+		if !ok {
+			return "", false, &AssetError{URL: uri, Err: fmt.Errorf("asset %s not found in checksums file %s", asset, checksumsURL)}
+		}
+
+		return sha256, true, nil
+	}
+
+	for _, candidate := range autoChecksumsFiles {
+		candidateURL, err := siblingURL(uri, strings.ReplaceAll(candidate, "{{ .Asset }}", asset))
+		if err != nil {
+			return "", false, &AssetError{URL: uri, Err: err}
+		}
+
+		checksums, err := c.get(ctx, candidateURL, false)
+		if err != nil {
+			logrus.Debugf("checksums file %s not available. error: %v", candidateURL, err)
+			continue
+		}
+
+		if sha256, ok := lookupChecksum(checksums, asset); ok {
+			logrus.Infof("using sha256 for %s from %s", uri, candidateURL)
+			return sha256, true, nil
+		}
+	}
+
+	logrus.Infof("no checksums file found for %s, will download it", uri)
+	return "", false, nil
+}
+
+// get downloads and parses the checksums file. retry is disabled when
+// probing for checksums files, as they are expected to be missing
+func (c *checksumsResolver) get(ctx context.Context, checksumsURL string, retry bool) (map[string]string, error) {
+	if checksums, ok := c.files[checksumsURL]; ok {
+		return checksums, nil
+	}
+
+	var data []byte
+	var err error
+	if retry {
+		data, err = downloadChecksums(ctx, checksumsURL)
+	} else {
+		data, err = downloadChecksumsOnce(ctx, checksumsURL)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	checksums, err := parseChecksums(data)
+	if err != nil {
+		return nil, &AssetError{URL: checksumsURL, Err: err}
+	}
+
+	c.files[checksumsURL] = checksums
+	return checksums, nil
+}
+
+func downloadChecksums(ctx context.Context, checksumsURL string) ([]byte, error) {
+	resp, attempts, err := getAsset(ctx, checksumsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &AssetError{URL: checksumsURL, StatusCode: resp.StatusCode, Attempts: attempts, Err: err}
+	}
+
+	return data, nil
+}
+
+func downloadChecksumsOnce(ctx context.Context, checksumsURL string) ([]byte, error) {
+	resp, err := get(ctx, checksumsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d, expected: %d", resp.StatusCode, http.StatusOK)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// verifyChecksums returns true if one of the assets resolved
+// from checksums file should be downloaded and verified
+func verifyChecksums() bool {
+	return os.Getenv("INPUT_VERIFY_CHECKSUMS") == "true"
+}
+
+// verifyRandomChecksum downloads one of the assets at random and
+// verifies its sha256 matches the one from the checksums file
+func verifyRandomChecksum(ctx context.Context, shas map[string]string) error {
+	if len(shas) == 0 {
+		return nil
+	}
+
+	uris := []string{}
+	for uri := range shas {
+		uris = append(uris, uri)
+	}
+
+	uri := uris[rand.IntN(len(uris))]
+	logrus.Infof("verifying sha256 of %s against checksums file", uri)
+	sha256, err := getSha256ForAsset(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	if sha256 != shas[uri] {
+		return &AssetError{URL: uri, Err: fmt.Errorf("sha256 mismatch. checksums file: %s, downloaded asset: %s", shas[uri], sha256)}
+	}
+
+	return nil
+}
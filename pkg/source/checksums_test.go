@@ -0,0 +1,252 @@
+package source
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	darwinSha256 = "0c2e4a2e4f1a9e5b3a76c11b1a0a6f1c1f0c54a5c4b1f6a0c1cbd7d62b7f8a11"
+	linuxSha256  = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+)
+
+func TestParseChecksums(t *testing.T) {
+	testcases := []struct {
+		name          string
+		input         string
+		expected      map[string]string
+		expectedError string
+	}{
+		{
+			name: "goreleaser checksums file",
+			input: fmt.Sprintf(`%s  kubectl-whoami_v0.0.2_darwin_amd64.tar.gz
+%s  kubectl-whoami_v0.0.2_linux_amd64.tar.gz
+`, darwinSha256, linuxSha256),
+			expected: map[string]string{
+				"kubectl-whoami_v0.0.2_darwin_amd64.tar.gz": darwinSha256,
+				"kubectl-whoami_v0.0.2_linux_amd64.tar.gz":  linuxSha256,
+			},
+		},
+		{
+			name:  "sha256sum binary mode with path",
+			input: fmt.Sprintf("%s *dist/kubectl-whoami_v0.0.2_darwin_amd64.tar.gz\n", darwinSha256),
+			expected: map[string]string{
+				"kubectl-whoami_v0.0.2_darwin_amd64.tar.gz": darwinSha256,
+			},
+		},
+		{
+			name:  "bsd format",
+			input: fmt.Sprintf("SHA256 (kubectl-whoami_v0.0.2_darwin_amd64.tar.gz) = %s\n", darwinSha256),
+			expected: map[string]string{
+				"kubectl-whoami_v0.0.2_darwin_amd64.tar.gz": darwinSha256,
+			},
+		},
+		{
+			name:  "file with only sha256",
+			input: darwinSha256 + "\n",
+			expected: map[string]string{
+				"": darwinSha256,
+			},
+		},
+		{
+			name:          "invalid sha256",
+			input:         "# checksums\n\nnot-a-sha256  kubectl-whoami_v0.0.2_darwin_amd64.tar.gz",
+			expectedError: `invalid checksums file, line 3 is not in sha256sum or bsd format`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			checksums, err := parseChecksums([]byte(tc.input))
+			if tc.expectedError != "" {
+				assert.NotNil(t, err)
+				if err != nil {
+					assert.Equal(t, tc.expectedError, err.Error())
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, checksums)
+		})
+	}
+}
+
+func TestRenderTemplateFromChecksums(t *testing.T) {
+	darwinArchive := "darwin-archive"
+	sum := sha256.Sum256([]byte(darwinArchive))
+	darwinArchiveSha256 := hex.EncodeToString(sum[:])
+
+	testcases := []struct {
+		name              string
+		checksumsURL      string
+		alsoAddURIAndSha  bool
+		files             map[string]string
+		setup             func()
+		expectedSha256    string
+		expectedDownloads []string
+		expectedError     string
+	}{
+		{
+			name:         "sha256 from checksums file",
+			checksumsURL: "{{ .TagName }}/checksums.txt",
+			files: map[string]string{
+				"/v0.0.2/checksums.txt": fmt.Sprintf("%s  darwin_amd64.tar.gz\n%s  linux_amd64.tar.gz\n", darwinSha256, linuxSha256),
+			},
+			expectedSha256:    darwinSha256,
+			expectedDownloads: []string{"/v0.0.2/checksums.txt"},
+		},
+		{
+			name:         "asset not in checksums file",
+			checksumsURL: "{{ .TagName }}/checksums.txt",
+			files: map[string]string{
+				"/v0.0.2/checksums.txt": fmt.Sprintf("%s  linux_amd64.tar.gz\n", linuxSha256),
+			},
+			expectedError: "asset SERVER_URL/v0.0.2/darwin_amd64.tar.gz: asset darwin_amd64.tar.gz not found in checksums file SERVER_URL/v0.0.2/checksums.txt",
+		},
+		{
+			name:         "auto mode uses <asset>.sha256",
+			checksumsURL: "auto",
+			files: map[string]string{
+				"/v0.0.2/darwin_amd64.tar.gz.sha256": darwinSha256,
+			},
+			expectedSha256:    darwinSha256,
+			expectedDownloads: []string{"/v0.0.2/darwin_amd64.tar.gz.sha256"},
+		},
+		{
+			name:         "auto mode uses checksums.txt",
+			checksumsURL: "auto",
+			files: map[string]string{
+				"/v0.0.2/checksums.txt": fmt.Sprintf("%s  darwin_amd64.tar.gz\n", darwinSha256),
+			},
+			expectedSha256:    darwinSha256,
+			expectedDownloads: []string{"/v0.0.2/darwin_amd64.tar.gz.sha256", "/v0.0.2/checksums.txt"},
+		},
+		{
+			name:         "auto mode falls back to downloading the asset",
+			checksumsURL: "auto",
+			files: map[string]string{
+				"/v0.0.2/darwin_amd64.tar.gz": darwinArchive,
+			},
+			expectedSha256: darwinArchiveSha256,
+			expectedDownloads: []string{
+				"/v0.0.2/darwin_amd64.tar.gz.sha256",
+				"/v0.0.2/checksums.txt",
+				"/v0.0.2/SHA256SUMS",
+				"/v0.0.2/sha256sums.txt",
+				"/v0.0.2/darwin_amd64.tar.gz",
+			},
+		},
+		{
+			name:         "verify checksums matches",
+			checksumsURL: "{{ .TagName }}/checksums.txt",
+			setup: func() {
+				os.Setenv("INPUT_VERIFY_CHECKSUMS", "true")
+			},
+			files: map[string]string{
+				"/v0.0.2/checksums.txt":       fmt.Sprintf("%s  darwin_amd64.tar.gz\n", darwinArchiveSha256),
+				"/v0.0.2/darwin_amd64.tar.gz": darwinArchive,
+			},
+			expectedSha256:    darwinArchiveSha256,
+			expectedDownloads: []string{"/v0.0.2/checksums.txt", "/v0.0.2/darwin_amd64.tar.gz"},
+		},
+		{
+			name:         "verify checksums mismatch",
+			checksumsURL: "{{ .TagName }}/checksums.txt",
+			setup: func() {
+				os.Setenv("INPUT_VERIFY_CHECKSUMS", "true")
+			},
+			files: map[string]string{
+				"/v0.0.2/checksums.txt":       fmt.Sprintf("%s  darwin_amd64.tar.gz\n", darwinSha256),
+				"/v0.0.2/darwin_amd64.tar.gz": darwinArchive,
+			},
+			expectedError: fmt.Sprintf("asset SERVER_URL/v0.0.2/darwin_amd64.tar.gz: sha256 mismatch. checksums file: %s, downloaded asset: %s", darwinSha256, darwinArchiveSha256),
+		},
+		{
+			name:             "asset also referenced by addURIAndSha is downloaded",
+			checksumsURL:     "{{ .TagName }}/checksums.txt",
+			alsoAddURIAndSha: true,
+			files: map[string]string{
+				"/v0.0.2/checksums.txt":       fmt.Sprintf("%s  darwin_amd64.tar.gz\n", darwinArchiveSha256),
+				"/v0.0.2/darwin_amd64.tar.gz": darwinArchive,
+			},
+			expectedSha256:    darwinArchiveSha256,
+			expectedDownloads: []string{"/v0.0.2/checksums.txt", "/v0.0.2/darwin_amd64.tar.gz"},
+		},
+		{
+			name:             "asset also referenced by addURIAndSha does not match checksums file",
+			checksumsURL:     "{{ .TagName }}/checksums.txt",
+			alsoAddURIAndSha: true,
+			files: map[string]string{
+				"/v0.0.2/checksums.txt":       fmt.Sprintf("%s  darwin_amd64.tar.gz\n", darwinSha256),
+				"/v0.0.2/darwin_amd64.tar.gz": darwinArchive,
+			},
+			expectedError: fmt.Sprintf("asset SERVER_URL/v0.0.2/darwin_amd64.tar.gz: sha256 mismatch. checksums file: %s, downloaded asset: %s", darwinSha256, darwinArchiveSha256),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			var mu sync.Mutex
+			downloads := []string{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				downloads = append(downloads, r.URL.Path)
+				mu.Unlock()
+
+				content, ok := tc.files[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				_, _ = w.Write([]byte(content))
+			}))
+			defer srv.Close()
+
+			checksumsURL := tc.checksumsURL
+			if checksumsURL != "auto" {
+				checksumsURL = srv.URL + "/" + checksumsURL
+			}
+
+			tmpl := fmt.Sprintf(`{{addURIAndShaFromChecksums "%s/{{ .TagName }}/darwin_amd64.tar.gz" "%s" .TagName }}`, srv.URL, checksumsURL)
+			expectedOutput := fmt.Sprintf("uri: %s/v0.0.2/darwin_amd64.tar.gz\n    sha256: %s", srv.URL, tc.expectedSha256)
+			if tc.alsoAddURIAndSha {
+				tmpl += fmt.Sprintf("\n{{addURIAndSha \"%s/{{ .TagName }}/darwin_amd64.tar.gz\" .TagName }}", srv.URL)
+				expectedOutput += "\n" + expectedOutput
+			}
+
+			templateFile := filepath.Join(t.TempDir(), ".krew.yaml")
+			err := os.WriteFile(templateFile, []byte(tmpl), 0644)
+			assert.Nil(t, err)
+
+			output, err := RenderTemplate(templateFile, ReleaseRequest{TagName: "v0.0.2"})
+			if tc.expectedError != "" {
+				assert.NotNil(t, err)
+				if err != nil {
+					assert.Equal(t, strings.ReplaceAll(tc.expectedError, "SERVER_URL", srv.URL), err.Error())
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, expectedOutput, string(output))
+			assert.Equal(t, tc.expectedDownloads, downloads)
+		})
+	}
+}
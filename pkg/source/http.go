@@ -5,42 +5,164 @@ package source
 // not work very well with go-retryablehttp as gock replaces transport required by go-retryablehttp
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"math"
+	"math/rand/v2"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
-	maxRetries   = 4
+	// maxRetries is the number of attempts for network errors, rate limits and server errors
+	maxRetries = 4
+
+	// maxNotFoundRetries is the number of attempts when the asset is not found. it is
+	// larger than maxRetries, as assets are often still uploading when the release is published
+	maxNotFoundRetries = 15
+
+	// defaultDownloadTimeout is the overall deadline for downloading
+	// all the assets of a release, including retries
+	defaultDownloadTimeout = 10 * time.Minute
+)
+
+// these are vars so that tests can use shorter waits
+var (
 	retryWaitMin = 2 * time.Second
 	retryWaitMax = 10 * time.Second
 )
 
+// downloadTimeout returns the overall deadline for downloading assets
+// from INPUT_DOWNLOAD_TIMEOUT env, e.g. '5m'
+func downloadTimeout() time.Duration {
+	v := os.Getenv("INPUT_DOWNLOAD_TIMEOUT")
+	if v == "" {
+		return defaultDownloadTimeout
+	}
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout <= 0 {
+		logrus.Warnf("invalid download timeout %q, using default %s", v, defaultDownloadTimeout)
+		return defaultDownloadTimeout
+	}
+
+	return timeout
+}
+
 // getWithRetry is basically http.Get with retries
 // we cannot use RoundTripper as gock (lib we use for testing)
-// overrides the Transport and thus we cannot test our retryable transport
-func getWithRetry(uri string) (*http.Response, error) {
+// overrides the Transport and thus we cannot test our retryable transport.
+// it returns the number of attempts made along with the response. the asset
+// not being found is retried upto maxNotFoundRetries, and other failures upto maxRetries
+func getWithRetry(ctx context.Context, uri string) (*http.Response, int, error) {
 	var resp *http.Response
 	var err error
 
-	for i := 0; i < maxRetries; i++ {
-		resp, err = http.Get(uri)
-		shouldRetry := checkRetry(resp, err)
+	attempts, failures, notFoundFailures := 0, 0, 0
+	for {
+		// the request is created for every attempt, as the asset might be resolved
+		// using github releases api only after it is uploaded
+		req, reqErr := newAssetRequest(ctx, uri)
+		if reqErr != nil {
+			// retrying will not fix an invalid request
+			return nil, attempts, fmt.Errorf("creating request for %s failed. error: %v", uri, reqErr)
+		}
+
+		attempts++
+		resp, err = http.DefaultClient.Do(req)
+		shouldRetry, reason, wait := checkRetry(ctx, resp, err)
 		if !shouldRetry {
 			break
 		}
 
-		drainBody(resp.Body)
-		wait := backoff(retryWaitMin, retryWaitMax, i)
-		<-time.After(wait)
+		limit, failed := maxRetries, &failures
+		if err == nil && resp.StatusCode == http.StatusNotFound {
+			limit, failed = maxNotFoundRetries, &notFoundFailures
+		}
+
+		*failed++
+		if *failed >= limit {
+			break
+		}
+
+		if resp != nil {
+			drainBody(resp.Body)
+		}
+
+		if wait == 0 {
+			wait = backoff(retryWaitMin, retryWaitMax, *failed-1)
+		}
+
+		logrus.Infof("retrying %s in %s, attempt %d failed: %s", uri, wait.Round(time.Millisecond), attempts, reason)
+		select {
+		case <-ctx.Done():
+			return nil, attempts, fmt.Errorf("giving up after %d attempt(s), last attempt failed: %s. error: %v", attempts, reason, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	return resp, attempts, err
+}
+
+// get is http.Get, with the request authenticated if credentials are available
+func get(ctx context.Context, uri string) (*http.Response, error) {
+	req, err := newAssetRequest(ctx, uri)
+	if err != nil {
+		return nil, err
 	}
 
-	return resp, err
+	return http.DefaultClient.Do(req)
 }
 
-func checkRetry(resp *http.Response, _ error) bool {
-	return resp.StatusCode == http.StatusNotFound
+// checkRetry returns if the request should be retried, the reason for
+// it and the time to wait before retrying, if the server asked for it
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, string, time.Duration) {
+	if err != nil {
+		// context cancelled or deadline exceeded, no point retrying
+		if ctx.Err() != nil {
+			return false, "", 0
+		}
+
+		return true, fmt.Sprintf("network error: %v", err), 0
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// assets might still be uploading
+		return true, "asset not found, it might still be uploading", 0
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, "rate limited", retryAfter(resp)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return true, fmt.Sprintf("server error, status code: %d", resp.StatusCode), retryAfter(resp)
+	}
+
+	return false, "", 0
+}
+
+// retryAfter parses the Retry-After header, which is
+// either a number of seconds or a http date
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
 }
 
 func drainBody(b io.ReadCloser) {
@@ -48,11 +170,14 @@ func drainBody(b io.ReadCloser) {
 	_, _ = io.Copy(io.Discard, io.LimitReader(b, int64(4096)))
 }
 
+// backoff returns exponential backoff with jitter, between half and full
+// of the exponential wait, so that parallel downloads do not retry in lockstep
 func backoff(min, max time.Duration, attemptNum int) time.Duration {
 	mult := math.Pow(2, float64(attemptNum)) * float64(min)
 	sleep := time.Duration(mult)
 	if float64(sleep) != mult || sleep > max {
 		sleep = max
 	}
-	return sleep
+
+	return sleep/2 + rand.N(sleep/2+1)
 }
@@ -0,0 +1,232 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// keep the retries fast in tests
+	retryWaitMin = 10 * time.Millisecond
+	retryWaitMax = 50 * time.Millisecond
+
+	os.Exit(m.Run())
+}
+
+func TestGetWithRetry(t *testing.T) {
+	testcases := []struct {
+		name               string
+		responses          []func(w http.ResponseWriter)
+		closeServer        bool
+		uri                string
+		expectedAttempts   int
+		expectedStatusCode int
+		expectedError      string
+		minDuration        time.Duration
+	}{
+		{
+			name: "success on first attempt",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusOK),
+			},
+			expectedAttempts:   1,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "asset still uploading",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusNotFound),
+				status(http.StatusNotFound),
+				status(http.StatusOK),
+			},
+			expectedAttempts:   3,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "asset never uploaded",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusNotFound),
+			},
+			expectedAttempts:   15,
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "asset still uploading after server errors",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusBadGateway),
+				status(http.StatusBadGateway),
+				status(http.StatusBadGateway),
+				status(http.StatusNotFound),
+				status(http.StatusNotFound),
+				status(http.StatusNotFound),
+				status(http.StatusNotFound),
+				status(http.StatusOK),
+			},
+			expectedAttempts:   8,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "server error retries exhausted",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable),
+			},
+			expectedAttempts:   4,
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "server error is retried",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusBadGateway),
+				status(http.StatusServiceUnavailable),
+				status(http.StatusOK),
+			},
+			expectedAttempts:   3,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "rate limited with Retry-After",
+			responses: []func(w http.ResponseWriter){
+				func(w http.ResponseWriter) {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+				status(http.StatusOK),
+			},
+			expectedAttempts:   2,
+			expectedStatusCode: http.StatusOK,
+			minDuration:        time.Second,
+		},
+		{
+			name: "client error is not retried",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusForbidden),
+			},
+			expectedAttempts:   1,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:             "network error is retried",
+			closeServer:      true,
+			expectedAttempts: 4,
+			expectedError:    "connection refused",
+		},
+		{
+			name:             "invalid request is not retried",
+			uri:              "http://[::1/kubectl-whoami.tar.gz",
+			expectedAttempts: 0,
+			expectedError:    `creating request for http://[::1/kubectl-whoami.tar.gz failed. error: parse "http://[::1/kubectl-whoami.tar.gz": missing ']' in host`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := requests
+				if i >= len(tc.responses) {
+					i = len(tc.responses) - 1
+				}
+
+				requests++
+				tc.responses[i](w)
+			}))
+
+			if tc.closeServer {
+				srv.Close()
+			} else {
+				defer srv.Close()
+			}
+
+			uri := srv.URL + "/kubectl-whoami.tar.gz"
+			if tc.uri != "" {
+				uri = tc.uri
+			}
+
+			start := time.Now()
+			resp, attempts, err := getWithRetry(context.Background(), uri)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+			assert.True(t, time.Since(start) >= tc.minDuration)
+
+			if tc.expectedError != "" {
+				if assert.NotNil(t, err) {
+					assert.True(t, strings.Contains(err.Error(), tc.expectedError), err.Error())
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.expectedAttempts, requests)
+		})
+	}
+}
+
+func TestGetWithRetryContextCancelled(t *testing.T) {
+	os.Clearenv()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, attempts, err := getWithRetry(ctx, srv.URL+"/kubectl-whoami.tar.gz")
+	assert.Equal(t, 1, attempts)
+	assert.True(t, time.Since(start) < 5*time.Second)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "giving up after 1 attempt(s), last attempt failed: rate limited. error: context deadline exceeded", err.Error())
+	}
+}
+
+func TestDownloadTimeout(t *testing.T) {
+	testcases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{
+			name:     "not set",
+			expected: defaultDownloadTimeout,
+		},
+		{
+			name:     "valid duration",
+			value:    "2m30s",
+			expected: 150 * time.Second,
+		},
+		{
+			name:     "invalid duration",
+			value:    "forever",
+			expected: defaultDownloadTimeout,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			os.Clearenv()
+			if tc.value != "" {
+				os.Setenv("INPUT_DOWNLOAD_TIMEOUT", tc.value)
+			}
+
+			assert.Equal(t, tc.expected, downloadTimeout())
+		})
+	}
+}
+
+func status(code int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(code)
+	}
+}
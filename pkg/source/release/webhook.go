@@ -0,0 +1,113 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+)
+
+const (
+	signaturePrefix = "sha256="
+
+	// defaultTemplateFile is the template file fetched from the plugin repo
+	defaultTemplateFile = ".krew.yaml"
+)
+
+// IgnoredEventError is returned for webhook events that do not release a plugin,
+// e.g. ping event, or release that is not published or is a pre-release
+type IgnoredEventError struct {
+	Reason string
+}
+
+func (e *IgnoredEventError) Error() string {
+	return fmt.Sprintf("ignoring event. %s", e.Reason)
+}
+
+// GithubRelease handles the github 'release' webhook events
+type GithubRelease struct {
+	// Secret is the secret of the webhook, used to verify X-Hub-Signature-256 header
+	Secret string
+}
+
+// NewGithubRelease gets new github release webhook instance
+func NewGithubRelease() (*GithubRelease, error) {
+	return &GithubRelease{
+		Secret: os.Getenv("KREW_RELEASE_BOT_GITHUB_WEBHOOK_SECRET"),
+	}, nil
+}
+
+// Parse validates the request. The returned release request does not have
+// the processed template, which has to be rendered from the plugin repo
+func (w *GithubRelease) Parse(r *http.Request) (*source.ReleaseRequest, error) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.parse(r.Header.Get, body)
+}
+
+func (w *GithubRelease) parse(header func(string) string, body []byte) (*source.ReleaseRequest, error) {
+	err := w.verify(header(github.SHA256SignatureHeader), body)
+	if err != nil {
+		return nil, err
+	}
+
+	eventType := header(github.EventTypeHeader)
+	if eventType != "release" {
+		return nil, &IgnoredEventError{Reason: fmt.Sprintf("event type is %q", eventType)}
+	}
+
+	event := &github.ReleaseEvent{}
+	err = json.Unmarshal(body, event)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.GetAction() != "published" {
+		return nil, &IgnoredEventError{Reason: fmt.Sprintf("release action is %q", event.GetAction())}
+	}
+
+	release := event.GetRelease()
+	if release.GetDraft() || release.GetPrerelease() {
+		return nil, &IgnoredEventError{Reason: fmt.Sprintf("release %s is a draft or pre-release", release.GetTagName())}
+	}
+
+	actor := release.GetAuthor().GetLogin()
+	if actor == "" {
+		actor = event.GetSender().GetLogin()
+	}
+
+	return &source.ReleaseRequest{
+		TagName:            release.GetTagName(),
+		PluginOwner:        event.GetRepo().GetOwner().GetLogin(),
+		PluginRepo:         event.GetRepo().GetName(),
+		PluginReleaseActor: actor,
+		TemplateFile:       defaultTemplateFile,
+	}, nil
+}
+
+// verify verifies the X-Hub-Signature-256 signature of the body
+func (w *GithubRelease) verify(signature string, body []byte) error {
+	if w.Secret == "" {
+		return &source.AuthError{StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("webhook secret not configured")}
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return &source.AuthError{StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("missing %s header", github.SHA256SignatureHeader)}
+	}
+
+	err := github.ValidateSignature(signature, body, []byte(w.Secret))
+	if err != nil {
+		return &source.AuthError{StatusCode: http.StatusUnauthorized, Err: fmt.Errorf("invalid signature")}
+	}
+
+	return nil
+}
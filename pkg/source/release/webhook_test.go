@@ -0,0 +1,136 @@
+package release
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rajatjindal/krew-release-bot/pkg/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertError(t *testing.T, expectedError string, err error) {
+	if expectedError == "" {
+		assert.Nil(t, err)
+	}
+
+	if expectedError != "" {
+		assert.NotNil(t, err)
+		if err != nil {
+			assert.Equal(t, expectedError, err.Error())
+		}
+	}
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParse(t *testing.T) {
+	published, err := os.ReadFile("data/release-published.json")
+	assert.Nil(t, err)
+
+	prerelease := []byte(strings.Replace(string(published), `"prerelease": false`, `"prerelease": true`, 1))
+	created := []byte(strings.Replace(string(published), `"action": "published"`, `"action": "created"`, 1))
+
+	testcases := []struct {
+		name            string
+		secret          string
+		eventType       string
+		body            []byte
+		signature       func(body []byte) string
+		expectedRequest *source.ReleaseRequest
+		expectedError   string
+		expectedIgnored bool
+	}{
+		{
+			name:      "release published",
+			secret:    "my-secret",
+			eventType: "release",
+			body:      published,
+			signature: func(body []byte) string { return sign("my-secret", body) },
+			expectedRequest: &source.ReleaseRequest{
+				TagName:            "v0.0.2",
+				PluginOwner:        "foo-bar",
+				PluginRepo:         "my-awesome-plugin",
+				PluginReleaseActor: "plugin-author",
+				TemplateFile:       ".krew.yaml",
+			},
+		},
+		{
+			name:            "pre-release is ignored",
+			secret:          "my-secret",
+			eventType:       "release",
+			body:            prerelease,
+			signature:       func(body []byte) string { return sign("my-secret", body) },
+			expectedError:   "ignoring event. release v0.0.2 is a draft or pre-release",
+			expectedIgnored: true,
+		},
+		{
+			name:            "release created is ignored",
+			secret:          "my-secret",
+			eventType:       "release",
+			body:            created,
+			signature:       func(body []byte) string { return sign("my-secret", body) },
+			expectedError:   `ignoring event. release action is "created"`,
+			expectedIgnored: true,
+		},
+		{
+			name:            "ping is ignored",
+			secret:          "my-secret",
+			eventType:       "ping",
+			body:            []byte(`{"zen": "Keep it logically awesome."}`),
+			signature:       func(body []byte) string { return sign("my-secret", body) },
+			expectedError:   `ignoring event. event type is "ping"`,
+			expectedIgnored: true,
+		},
+		{
+			name:          "invalid signature",
+			secret:        "my-secret",
+			eventType:     "release",
+			body:          published,
+			signature:     func(body []byte) string { return sign("other-secret", body) },
+			expectedError: "invalid signature",
+		},
+		{
+			name:          "missing signature",
+			secret:        "my-secret",
+			eventType:     "release",
+			body:          published,
+			signature:     func(body []byte) string { return "" },
+			expectedError: "missing X-Hub-Signature-256 header",
+		},
+		{
+			name:          "secret not configured",
+			eventType:     "release",
+			body:          published,
+			signature:     func(body []byte) string { return sign("my-secret", body) },
+			expectedError: "webhook secret not configured",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/github-release-webhook", bytes.NewReader(tc.body))
+			assert.Nil(t, err)
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("X-GitHub-Event", tc.eventType)
+			r.Header.Set("X-Hub-Signature-256", tc.signature(tc.body))
+
+			hook := &GithubRelease{Secret: tc.secret}
+			request, err := hook.Parse(r)
+			assertError(t, tc.expectedError, err)
+			assert.Equal(t, tc.expectedRequest, request)
+
+			_, ignored := err.(*IgnoredEventError)
+			assert.Equal(t, tc.expectedIgnored, ignored)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package source
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -8,23 +9,63 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"time"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
+// AssetError is the error returned when processing a release asset fails
+type AssetError struct {
+	// URL is the url of the asset, or the url template
+	// if it failed to render
+	URL        string
+	StatusCode int
+	Attempts   int
+	Err        error
+}
+
+func (e *AssetError) Error() string {
+	return fmt.Sprintf("asset %s: %v", e.URL, e.Err)
+}
+
+func (e *AssetError) Unwrap() error {
+	return e.Err
+}
+
+// maxConcurrentDownloads is the max number of assets downloaded in parallel
+const maxConcurrentDownloads = 4
+
+// getAsset gets the asset, returning an AssetError if it fails
+func getAsset(ctx context.Context, uri string) (*http.Response, int, error) {
+	resp, attempts, err := getWithRetry(ctx, uri)
+	if err != nil {
+		return nil, attempts, &AssetError{URL: uri, Attempts: attempts, Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		drainBody(resp.Body)
+		return nil, attempts, &AssetError{
+			URL:        uri,
+			StatusCode: resp.StatusCode,
+			Attempts:   attempts,
+			Err:        fmt.Errorf("downloading failed after %d attempt(s). status code: %d, expected: %d", attempts, resp.StatusCode, http.StatusOK),
+		}
+	}
+
+	return resp, attempts, nil
+}
+
 // DownloadFileWithName downloads a file with name
 func DownloadFileWithName(uri, name string) (string, error) {
-	resp, err := getWithRetry(uri)
+	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout())
+	defer cancel()
+
+	resp, attempts, err := getAsset(ctx, uri)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("downloading file %s failed. status code: %d, expected: %d", uri, resp.StatusCode, http.StatusOK)
-	}
-
 	dir, err := os.MkdirTemp("", "")
 	if err != nil {
 		return "", err
@@ -39,43 +80,90 @@ func DownloadFileWithName(uri, name string) (string, error) {
 
 	_, err = io.Copy(out, resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to save file %s. error: %v", file, err)
+		return "", &AssetError{URL: uri, StatusCode: resp.StatusCode, Attempts: attempts, Err: fmt.Errorf("failed to save file %s. error: %v", file, err)}
 	}
 
 	logrus.Infof("downloaded file %s", file)
 	return file, nil
 }
 
-func downloadFile(uri string) (string, error) {
-	return DownloadFileWithName(uri, fmt.Sprintf("%d", time.Now().Unix()))
-}
-
-func getSha256ForAsset(uri string) (string, error) {
-	file, err := downloadFile(uri)
+// getSha256ForAsset streams the asset into the hash
+// without saving it to disk
+func getSha256ForAsset(ctx context.Context, uri string) (string, error) {
+	logrus.Infof("getting sha256 for %s", uri)
+	resp, attempts, err := getAsset(ctx, uri)
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 
-	defer os.Remove(file)
-	sha256, err := getSha256(file)
-	if err != nil {
-		return "", err
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", &AssetError{URL: uri, StatusCode: resp.StatusCode, Attempts: attempts, Err: fmt.Errorf("failed to read asset. error: %v", err)}
 	}
 
-	return sha256, nil
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-func getSha256(filename string) (string, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return "", err
+// getSha256ForAssets downloads and hashes the assets using a bounded
+// pool of workers. it returns a map of uri to sha256 of the asset.
+// the first asset that fails cancels the remaining downloads, and its error is returned
+func getSha256ForAssets(ctx context.Context, uris []string) (map[string]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	shas := map[string]string{}
+	jobs := make(chan string)
+
+	workers := maxConcurrentDownloads
+	if len(uris) < workers {
+		workers = len(uris)
 	}
-	defer f.Close()
 
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return "", err
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Go(func() {
+			for uri := range jobs {
+				// the job may be received after the downloads are cancelled
+				if ctx.Err() != nil {
+					continue
+				}
+
+				sha256, err := getSha256ForAsset(ctx, uri)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+
+				shas[uri] = sha256
+				mu.Unlock()
+			}
+		})
 	}
 
-	return hex.EncodeToString(h.Sum(nil)), nil
+send:
+	for _, uri := range uris {
+		select {
+		case jobs <- uri:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// the deadline of parent context may have stopped sending the jobs
+	if err := ctx.Err(); err != nil && len(shas) < len(uris) {
+		return nil, err
+	}
+
+	return shas, nil
 }
@@ -18,4 +18,24 @@ type ReleaseRequest struct {
 	PluginReleaseActor string `json:"pluginReleaseActor"`
 	TemplateFile       string `json:"templateFile"`
 	ProcessedTemplate  []byte `json:"processedTemplate"`
+
+	// Index is the owner/repo of krew index to release the plugin to.
+	// the default index of the bot is used if empty
+	Index string `json:"index,omitempty"`
+}
+
+// AuthError is returned when the caller of the webhook is not authorized
+// to release the plugin. StatusCode is 401 when the caller could not be
+// authenticated, and 403 when it is not allowed to release the plugin
+type AuthError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
 }
@@ -2,6 +2,8 @@ package source
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"path"
 	"strings"
@@ -51,39 +53,226 @@ func ProcessTemplate(templateFile string, values interface{}) (string, []byte, e
 	return pluginName, spec, nil
 }
 
-//RenderTemplate process the .krew.yaml template for the release request
+// renderAssetURL renders the url template with tag
+func renderAssetURL(url, tag string) (string, error) {
+	t := struct {
+		TagName string
+	}{
+		TagName: tag,
+	}
+	buf := new(bytes.Buffer)
+	temp, err := template.New("url").Parse(url)
+	if err != nil {
+		return "", &AssetError{URL: url, Err: err}
+	}
+
+	err = temp.Execute(buf, t)
+	if err != nil {
+		return "", &AssetError{URL: url, Err: err}
+	}
+
+	return buf.String(), nil
+}
+
+// assetCollector collects the assets referenced by addURIAndSha and
+// addURIAndShaFromChecksums calls in the template, without downloading them
+type assetCollector struct {
+	uris []string
+	seen map[string]bool
+
+	// checksums is the map of asset uri to the checksums url for it
+	checksums map[string]string
+
+	// hashed is the set of asset uris referenced by addURIAndSha,
+	// which are downloaded even if they are in a checksums file
+	hashed map[string]bool
+}
+
+func newAssetCollector() *assetCollector {
+	return &assetCollector{
+		seen:      map[string]bool{},
+		checksums: map[string]string{},
+		hashed:    map[string]bool{},
+	}
+}
+
+func (c *assetCollector) addURIAndSha(url, tag string) (string, error) {
+	uri, err := renderAssetURL(url, tag)
+	if err != nil {
+		return "", err
+	}
+
+	if !c.seen[uri] {
+		c.seen[uri] = true
+		c.uris = append(c.uris, uri)
+	}
+
+	c.hashed[uri] = true
+	return "", nil
+}
+
+func (c *assetCollector) addURIAndShaFromChecksums(url, checksumsURL, tag string) (string, error) {
+	uri, err := renderAssetURL(url, tag)
+	if err != nil {
+		return "", err
+	}
+
+	if checksumsURL != checksumsAuto {
+		checksumsURL, err = renderAssetURL(checksumsURL, tag)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if !c.seen[uri] {
+		c.seen[uri] = true
+		c.uris = append(c.uris, uri)
+	}
+
+	c.checksums[uri] = checksumsURL
+	return "", nil
+}
+
+func (c *assetCollector) funcs() template.FuncMap {
+	return template.FuncMap{
+		"addURIAndSha":              c.addURIAndSha,
+		"addURIAndShaFromChecksums": c.addURIAndShaFromChecksums,
+	}
+}
+
+// resolve returns the map of asset uri to sha256. sha256 is taken from checksums
+// files where possible, remaining assets are downloaded and hashed concurrently.
+// assets referenced by addURIAndSha are always downloaded, and it is an error
+// if their sha256 does not match the one in checksums file
+func (c *assetCollector) resolve(ctx context.Context) (map[string]string, error) {
+	shas := map[string]string{}
+	fromChecksums := map[string]string{}
+	resolver := newChecksumsResolver()
+	download := []string{}
+	for _, uri := range c.uris {
+		checksumsURL, ok := c.checksums[uri]
+		if !ok {
+			download = append(download, uri)
+			continue
+		}
+
+		sha256, found, err := resolver.resolve(ctx, uri, checksumsURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found || c.hashed[uri] {
+			download = append(download, uri)
+		}
+
+		if found {
+			shas[uri] = sha256
+			fromChecksums[uri] = sha256
+		}
+	}
+
+	if verifyChecksums() {
+		// assets that are downloaded anyway are verified below
+		unverified := map[string]string{}
+		for uri, sha256 := range fromChecksums {
+			if !c.hashed[uri] {
+				unverified[uri] = sha256
+			}
+		}
+
+		err := verifyRandomChecksum(ctx, unverified)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	downloaded, err := getSha256ForAssets(ctx, download)
+	if err != nil {
+		return nil, err
+	}
+
+	for uri, sha256 := range downloaded {
+		if expected, ok := fromChecksums[uri]; ok && expected != sha256 {
+			return nil, &AssetError{URL: uri, Err: fmt.Errorf("sha256 mismatch. checksums file: %s, downloaded asset: %s", expected, sha256)}
+		}
+
+		shas[uri] = sha256
+	}
+
+	return shas, nil
+}
+
+// RenderTemplate process the .krew.yaml template for the release request.
+//
+// The template is executed twice. The first pass collects the assets referenced
+// by addURIAndSha, which are then downloaded and hashed concurrently. The second
+// pass renders the template using the calculated sha256 values.
 func RenderTemplate(templateFile string, values interface{}) ([]byte, error) {
 	logrus.Debugf("started processing of template %s", templateFile)
-	name := path.Base(templateFile)
-	t := template.New(name).Funcs(map[string]interface{}{
-		"indent": indent,
-		"addURIAndSha": func(url, tag string) string {
-			t := struct {
-				TagName string
-			}{
-				TagName: tag,
-			}
-			buf := new(bytes.Buffer)
-			temp, err := template.New("url").Parse(url)
-			if err != nil {
-				panic(err)
-			}
+	collector := newAssetCollector()
+	_, err := executeTemplate(templateFile, values, collector.funcs())
+	if err != nil {
+		return nil, err
+	}
 
-			err = temp.Execute(buf, t)
-			if err != nil {
-				panic(err)
-			}
+	ctx, cancel := context.WithTimeout(context.Background(), downloadTimeout())
+	defer cancel()
 
-			logrus.Infof("getting sha256 for %s", buf.String())
-			sha256, err := getSha256ForAsset(buf.String())
-			if err != nil {
-				panic(err)
-			}
+	shas, err := collector.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-			return fmt.Sprintf(`uri: %s
-    sha256: %s`, buf.String(), sha256)
+	uriAndSha := func(url, tag string) (string, error) {
+		uri, err := renderAssetURL(url, tag)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf(`uri: %s
+    sha256: %s`, uri, shas[uri]), nil
+	}
+
+	output, err := executeTemplate(templateFile, values, template.FuncMap{
+		"addURIAndSha": uriAndSha,
+		"addURIAndShaFromChecksums": func(url, _, tag string) (string, error) {
+			return uriAndSha(url, tag)
 		},
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Debugf("completed processing of template")
+	return output, nil
+}
+
+// GetAssetURLs returns the urls of the assets, and checksums files, referenced
+// by addURIAndSha and addURIAndShaFromChecksums in the template, without downloading them
+func GetAssetURLs(templateFile string, values interface{}) ([]string, error) {
+	collector := newAssetCollector()
+	_, err := executeTemplate(templateFile, values, collector.funcs())
+	if err != nil {
+		return nil, err
+	}
+
+	uris := append([]string{}, collector.uris...)
+	for _, uri := range collector.uris {
+		checksumsURL, ok := collector.checksums[uri]
+		if ok && checksumsURL != checksumsAuto && !collector.seen[checksumsURL] {
+			collector.seen[checksumsURL] = true
+			uris = append(uris, checksumsURL)
+		}
+	}
+
+	return uris, nil
+}
+
+func executeTemplate(templateFile string, values interface{}, assetFuncs template.FuncMap) ([]byte, error) {
+	name := path.Base(templateFile)
+	t := template.New(name).Funcs(template.FuncMap{
+		"indent": indent,
+	}).Funcs(assetFuncs)
 
 	templateObject, err := t.ParseFiles(templateFile)
 	if err != nil {
@@ -93,9 +282,15 @@ func RenderTemplate(templateFile string, values interface{}) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	err = templateObject.Execute(buf, values)
 	if err != nil {
+		// text/template wraps the error with the whole template path,
+		// return the asset error as is to make it easier to read
+		var assetErr *AssetError
+		if errors.As(err, &assetErr) {
+			return nil, assetErr
+		}
+
 		return nil, err
 	}
 
-	logrus.Debugf("completed processing of template")
 	return buf.Bytes(), nil
 }
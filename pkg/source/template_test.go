@@ -1,10 +1,19 @@
 package source
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
@@ -110,5 +119,184 @@ func TestRenderTemplateRetry(t *testing.T) {
 
 	_, err := DownloadFileWithName(srv.URL+"/rajatjindal/kubectl-whoami/releases/download/v0.0.2/kubectl-whoami_v0.0.2_darwin_amd64.tar.gz", "whoami")
 	assert.NotNil(t, err)
-	assert.Equal(t, 4, retries)
+	assert.Equal(t, maxNotFoundRetries, retries)
+
+	var assetErr *AssetError
+	if assert.True(t, errors.As(err, &assetErr)) {
+		assert.Equal(t, srv.URL+"/rajatjindal/kubectl-whoami/releases/download/v0.0.2/kubectl-whoami_v0.0.2_darwin_amd64.tar.gz", assetErr.URL)
+		assert.Equal(t, http.StatusNotFound, assetErr.StatusCode)
+		assert.Equal(t, maxNotFoundRetries, assetErr.Attempts)
+	}
+}
+
+func TestRenderTemplateParallelDownloads(t *testing.T) {
+	var mu sync.Mutex
+	inflight, maxInflight, requests := 0, 0, 0
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		inflight++
+		if inflight > maxInflight {
+			maxInflight = inflight
+		}
+		mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write([]byte(path.Base(r.URL.Path)))
+
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	platforms := []string{"darwin_amd64", "darwin_arm64", "linux_amd64", "linux_arm64", "linux_386", "windows_amd64"}
+	tmpl := "platforms:\n"
+	expected := "platforms:\n"
+	for _, p := range platforms {
+		tmpl += fmt.Sprintf("- os: %s\n  {{addURIAndSha \"%s/download/{{ .TagName }}/%s.tar.gz\" .TagName | indent 2 }}\n", p, srv.URL, p)
+		sum := sha256.Sum256([]byte(p + ".tar.gz"))
+		expected += fmt.Sprintf("- os: %s\n  uri: %s/download/v0.0.2/%s.tar.gz\n  sha256: %s\n", p, srv.URL, p, hex.EncodeToString(sum[:]))
+	}
+
+	// same asset referenced twice is downloaded once
+	tmpl += fmt.Sprintf("- os: again\n  {{addURIAndSha \"%s/download/{{ .TagName }}/linux_amd64.tar.gz\" .TagName | indent 2 }}\n", srv.URL)
+	sum := sha256.Sum256([]byte("linux_amd64.tar.gz"))
+	expected += fmt.Sprintf("- os: again\n  uri: %s/download/v0.0.2/linux_amd64.tar.gz\n  sha256: %s\n", srv.URL, hex.EncodeToString(sum[:]))
+
+	templateFile := filepath.Join(t.TempDir(), ".krew.yaml")
+	err := os.WriteFile(templateFile, []byte(tmpl), 0644)
+	assert.Nil(t, err)
+
+	output, err := RenderTemplate(templateFile, ReleaseRequest{TagName: "v0.0.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(output))
+	assert.Equal(t, len(platforms), requests)
+	assert.True(t, maxInflight > 1, "expected assets to be downloaded in parallel")
+	assert.True(t, maxInflight <= maxConcurrentDownloads, "expected at most %d parallel downloads, got %d", maxConcurrentDownloads, maxInflight)
+}
+
+func TestGetSha256ForAssetsStopsOnFirstError(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		if path.Base(r.URL.Path) == "broken.tar.gz" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// the other assets are slow, until the download is cancelled
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		_, _ = w.Write([]byte(path.Base(r.URL.Path)))
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	uris := []string{srv.URL + "/download/broken.tar.gz"}
+	for i := 0; i < 3*maxConcurrentDownloads; i++ {
+		uris = append(uris, fmt.Sprintf("%s/download/asset-%d.tar.gz", srv.URL, i))
+	}
+
+	start := time.Now()
+	_, err := getSha256ForAssets(context.Background(), uris)
+	assert.True(t, time.Since(start) < 5*time.Second, "expected the downloads to be cancelled")
+
+	var assetErr *AssetError
+	if assert.True(t, errors.As(err, &assetErr)) {
+		assert.Equal(t, srv.URL+"/download/broken.tar.gz", assetErr.URL)
+		assert.Equal(t, http.StatusForbidden, assetErr.StatusCode)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, requests <= maxConcurrentDownloads, "expected no downloads after the failure, got %d requests", requests)
+}
+
+func TestGetAssetURLs(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), ".krew.yaml")
+	tmpl := `{{addURIAndSha "https://github.com/foo/bar/releases/download/{{ .TagName }}/bar_linux.tar.gz" .TagName }}
+{{addURIAndShaFromChecksums "https://github.com/foo/bar/releases/download/{{ .TagName }}/bar_darwin.tar.gz" "https://github.com/foo/bar/releases/download/{{ .TagName }}/checksums.txt" .TagName }}
+{{addURIAndShaFromChecksums "https://github.com/foo/bar/releases/download/{{ .TagName }}/bar_windows.tar.gz" "auto" .TagName }}
+{{addURIAndSha "https://github.com/foo/bar/releases/download/{{ .TagName }}/bar_linux.tar.gz" .TagName }}`
+	err := os.WriteFile(templateFile, []byte(tmpl), 0644)
+	assert.Nil(t, err)
+
+	uris, err := GetAssetURLs(templateFile, ReleaseRequest{TagName: "v0.0.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"https://github.com/foo/bar/releases/download/v0.0.2/bar_linux.tar.gz",
+		"https://github.com/foo/bar/releases/download/v0.0.2/bar_darwin.tar.gz",
+		"https://github.com/foo/bar/releases/download/v0.0.2/bar_windows.tar.gz",
+		"https://github.com/foo/bar/releases/download/v0.0.2/checksums.txt",
+	}, uris)
+}
+
+func TestRenderTemplateAssetError(t *testing.T) {
+	testcases := []struct {
+		name               string
+		file               string
+		setup              func()
+		expectedURL        string
+		expectedStatusCode int
+		expectedError      string
+	}{
+		{
+			name: "asset download fails",
+			file: "data/needs-4-space-indentation.yaml",
+			setup: func() {
+				gock.New("https://github.com").
+					Times(4).
+					Get("/rajatjindal/kubectl-whoami/releases/download/v0.0.2/kubectl-whoami_v0.0.2_darwin_amd64.tar.gz").
+					Reply(500)
+			},
+			expectedURL:        "https://github.com/rajatjindal/kubectl-whoami/releases/download/v0.0.2/kubectl-whoami_v0.0.2_darwin_amd64.tar.gz",
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedError:      "asset https://github.com/rajatjindal/kubectl-whoami/releases/download/v0.0.2/kubectl-whoami_v0.0.2_darwin_amd64.tar.gz: downloading failed after 4 attempt(s). status code: 500, expected: 200",
+		},
+		{
+			name:          "url template is invalid",
+			file:          "data/invalid-url-template.yaml",
+			expectedURL:   "https://github.com/rajatjindal/kubectl-whoami/releases/download/{{ .TagName }/kubectl-whoami.tar.gz",
+			expectedError: `asset https://github.com/rajatjindal/kubectl-whoami/releases/download/{{ .TagName }/kubectl-whoami.tar.gz: template: url:1: unexpected "}" in operand`,
+		},
+	}
+
+	values := ReleaseRequest{
+		TagName: "v0.0.2",
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			gock.DisableNetworking()
+			defer gock.Off()
+
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			_, err := RenderTemplate(tc.file, values)
+			assert.NotNil(t, err)
+
+			var assetErr *AssetError
+			if assert.True(t, errors.As(err, &assetErr)) {
+				assert.Equal(t, tc.expectedURL, assetErr.URL)
+				assert.Equal(t, tc.expectedStatusCode, assetErr.StatusCode)
+				assert.Equal(t, tc.expectedError, assetErr.Error())
+			}
+		})
+	}
 }